@@ -0,0 +1,118 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// conventionalHeader matches the header of a conventional commit, e.g.
+// "feat(parser)!: allow ranges". The groups are the type and the optional !.
+var conventionalHeader = regexp.MustCompile(`^(\w+)(?:\([^)]*\))?(!)?: `)
+
+// gitLogCommit matches the line starting a commit in the default output of
+// git log.
+var gitLogCommit = regexp.MustCompile(`^commit [0-9a-f]{7,}`)
+
+// commitLevel returns the increment level implied by conventional commit
+// messages: major for a breaking change, minor for a feat and patch for a
+// fix. It returns an empty string if no commit requires a release.
+//
+// The messages are separated by NUL bytes, as by git log -z --format=%B, or
+// given as the default output of git log. Otherwise every line is a message
+// on its own, as by git log --format=%s. Only the first line of a message is
+// its header; the other lines are only searched for a BREAKING CHANGE footer.
+func commitLevel(r io.Reader) (string, error) {
+	levels := map[string]int{"": 0, "patch": 1, "minor": 2, "major": 3}
+	level := ""
+	raise := func(l string) {
+		if levels[l] > levels[level] {
+			level = l
+		}
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	for _, msg := range splitCommitMessages(string(data)) {
+		header := true
+		for _, line := range strings.Split(msg, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+
+			if strings.HasPrefix(line, "BREAKING CHANGE:") || strings.HasPrefix(line, "BREAKING-CHANGE:") {
+				raise("major")
+				continue
+			}
+			if !header {
+				continue
+			}
+			header = false
+
+			m := conventionalHeader.FindStringSubmatch(line)
+			switch {
+			case m == nil:
+			case m[2] == "!":
+				raise("major")
+			case strings.EqualFold(m[1], "feat"):
+				raise("minor")
+			case strings.EqualFold(m[1], "fix"):
+				raise("patch")
+			}
+		}
+	}
+
+	return level, nil
+}
+
+// splitCommitMessages splits the input of commitLevel into the messages.
+// Of the default git log output only the indented message lines are kept.
+func splitCommitMessages(s string) []string {
+	if strings.Contains(s, "\x00") {
+		return strings.Split(s, "\x00")
+	}
+
+	lines := strings.Split(s, "\n")
+	if len(lines) == 0 || !gitLogCommit.MatchString(lines[0]) {
+		return lines
+	}
+
+	msgs := []string{}
+	for _, line := range lines {
+		switch {
+		case gitLogCommit.MatchString(line):
+			msgs = append(msgs, "")
+		case strings.HasPrefix(line, "    "):
+			msgs[len(msgs)-1] += line + "\n"
+		}
+	}
+
+	return msgs
+}
+
+// mustReadCommitLevel reads the commit messages from file, - or an empty
+// name for stdin, and returns their increment level.
+func mustReadCommitLevel(file string) string {
+	var r io.Reader = os.Stdin
+	if file != "-" && file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			fatalf("Failed to read commits; %v", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	level, err := commitLevel(r)
+	if err != nil {
+		fatalf("Failed to read commits; %v", err)
+	}
+
+	return level
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	kingpin "github.com/alecthomas/kingpin/v2"
+)
+
+// The fish and PowerShell scripts use the same --completion-bash protocol
+// as the bash and zsh scripts which ship with kingpin.
+var fishCompletionTemplate = `
+function __{{.App.Name}}_complete
+    set -l args (commandline -opc)[2..-1]
+    set -l current (commandline -ct)
+    {{.App.Name}} --completion-bash $args "$current"
+end
+complete -c {{.App.Name}} -f -a '(__{{.App.Name}}_complete)'
+`
+
+var powershellCompletionTemplate = `
+Register-ArgumentCompleter -Native -CommandName {{.App.Name}} -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -eq '') {
+        $words += '""'
+    }
+    & {{.App.Name}} --completion-bash @words | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`
+
+var completionTemplates = map[string]string{
+	"bash":       kingpin.BashCompletionTemplate,
+	"zsh":        kingpin.ZshCompletionTemplate,
+	"fish":       fishCompletionTemplate,
+	"powershell": powershellCompletionTemplate,
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	semver "github.com/Masterminds/semver/v3"
+)
+
+// hyphenRange matches a range like "1.2 - 1.4.5".
+var hyphenRange = regexp.MustCompile(`(\S+)\s+-\s+(\S+)`)
+
+// constraintTerm matches a single comparison like "^1.2", ">= 1.0.0" or "1.x".
+var constraintTerm = regexp.MustCompile(`(?:(\^|~>?|>=|<=|!=|=|>|<)\s*)?(v?[0-9xX*]+(?:\.[0-9xX*]+)*(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?)`)
+
+// expandConstraints rewrites caret, tilde, wildcard and hyphen ranges of a
+// constraint string into plain comparisons, e.g. ^1.2.3 becomes
+// >=1.2.3, <2.0.0. Other comparisons are kept as they are.
+func expandConstraints(s string) string {
+	return expandConstraintBounds(s, "")
+}
+
+// expandConstraintBounds is expandConstraints appending upperPre to the
+// exclusive upper bounds of the ranges. With -0 they also exclude the
+// prereleases of the bound, e.g. ^1.2.3 becomes >=1.2.3, <2.0.0-0.
+func expandConstraintBounds(s, upperPre string) string {
+	groups := []string{}
+	for _, group := range strings.Split(s, "||") {
+		group = hyphenRange.ReplaceAllStringFunc(group, func(r string) string {
+			m := hyphenRange.FindStringSubmatch(r)
+			return ">=" + m[1] + " <=" + m[2]
+		})
+
+		terms := []string{}
+		for _, m := range constraintTerm.FindAllStringSubmatch(group, -1) {
+			terms = append(terms, expandTerm(m[1], m[2], upperPre)...)
+		}
+		groups = append(groups, strings.Join(terms, ", "))
+	}
+
+	return strings.Join(groups, " || ")
+}
+
+// expandTerm expands a single comparison into its bounds, appending upperPre
+// to the exclusive upper bound of a range.
+func expandTerm(op, v, upperPre string) []string {
+	core := strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(core, "+"); i >= 0 {
+		core = core[:i]
+	}
+	pre := ""
+	if i := strings.Index(core, "-"); i >= 0 {
+		core, pre = core[:i], core[i:]
+	}
+
+	// The numbers up to the first wildcard or missing component.
+	nums := []uint64{}
+	for _, p := range strings.Split(core, ".") {
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			break
+		}
+		nums = append(nums, n)
+	}
+
+	lower := make([]uint64, 3)
+	copy(lower, nums)
+	from := fmt.Sprintf(">=%d.%d.%d%s", lower[0], lower[1], lower[2], pre)
+
+	switch {
+	case op == "^":
+		switch {
+		case len(nums) == 0:
+			return []string{">=0.0.0"}
+		case nums[0] > 0 || len(nums) == 1:
+			return []string{from, upperBound(nums, 0, upperPre)}
+		case nums[1] > 0 || len(nums) == 2:
+			return []string{from, upperBound(nums, 1, upperPre)}
+		}
+		return []string{from, upperBound(nums, 2, upperPre)}
+	case op == "~" || op == "~>":
+		switch len(nums) {
+		case 0:
+			return []string{">=0.0.0"}
+		case 1:
+			return []string{from, upperBound(nums, 0, upperPre)}
+		}
+		return []string{from, upperBound(nums, 1, upperPre)}
+	case (op == "" || op == "=") && len(nums) < 3:
+		if len(nums) == 0 {
+			return []string{">=0.0.0"}
+		}
+		return []string{from, upperBound(nums, len(nums)-1, upperPre)}
+	case (op == ">" || op == ">=" || op == "<" || op == "<=") && len(nums) < 3:
+		// A wildcard or missing component stands for the whole range, so
+		// >1.2.x is >=1.3.0 and <=1.2.x is <1.3.0.
+		if len(nums) == 0 {
+			if op == "<" {
+				return []string{"<0.0.0"}
+			}
+			return []string{">=0.0.0"}
+		}
+
+		switch op {
+		case ">":
+			return []string{">=" + strings.TrimPrefix(upperBound(nums, len(nums)-1, ""), "<")}
+		case ">=":
+			return []string{from}
+		case "<":
+			if pre == "" {
+				return []string{"<" + strings.TrimPrefix(from, ">=") + upperPre}
+			}
+			return []string{"<" + strings.TrimPrefix(from, ">=")}
+		}
+		return []string{upperBound(nums, len(nums)-1, upperPre)}
+	}
+
+	return []string{op + v}
+}
+
+// upperBound returns the exclusive upper bound which increments the
+// component at index i of nums and drops all lower ones, with the
+// prerelease pre.
+func upperBound(nums []uint64, i int, pre string) string {
+	upper := make([]uint64, 3)
+	copy(upper, nums[:i+1])
+	upper[i]++
+
+	return fmt.Sprintf("<%d.%d.%d%s", upper[0], upper[1], upper[2], pre)
+}
+
+// constraintClause matches a hyphen range or a single comparison.
+var constraintClause = regexp.MustCompile(hyphenRange.String() + "|" + constraintTerm.String())
+
+// clauseResult is the outcome of a single clause of a constraint.
+type clauseResult struct {
+	Clause    string `json:"clause"`
+	Satisfied bool   `json:"satisfied"`
+	Reason    string `json:"reason"`
+}
+
+// explainClauses tests v against each clause of the constraint s on its own,
+// in the order they appear. Clauses separated by || are alternatives.
+func explainClauses(v *semver.Version, s string, includePrerelease bool) []clauseResult {
+	results := []clauseResult{}
+	for _, group := range strings.Split(s, "||") {
+		for _, c := range constraintClause.FindAllString(group, -1) {
+			c = strings.TrimSpace(c)
+
+			ok, msgs := mustParseConstraints(c).Validate(v)
+			if includePrerelease && v.Prerelease() != "" {
+				ok, msgs = validateIncludingPrerelease(v, c)
+			}
+
+			reason := ""
+			if len(msgs) > 0 {
+				reason = msgs[0].Error()
+			}
+			results = append(results, clauseResult{c, ok, reason})
+		}
+	}
+
+	return results
+}
+
+// comparisonTerm splits an expanded comparison into operator and version.
+var comparisonTerm = regexp.MustCompile(`^(>=|<=|!=|=|>|<)?(.*)$`)
+
+// validateIncludingPrerelease tests v against the expanded constraint s by
+// plain semver precedence. Unlike Constraints.Validate it does not reject
+// prerelease versions for comparisons without prerelease. The upper bounds
+// of ranges exclude their prereleases, as with npm's includePrerelease, so
+// 2.0.0-rc.1 does not satisfy ^1.0.0.
+func validateIncludingPrerelease(v *semver.Version, s string) (bool, []error) {
+	msgs := []error{}
+	for _, group := range strings.Split(expandConstraintBounds(s, "-0"), " || ") {
+		ok := true
+		for _, term := range strings.Split(group, ", ") {
+			m := comparisonTerm.FindStringSubmatch(term)
+			bound, err := semver.NewVersion(m[2])
+			if err != nil {
+				// What is not expanded, e.g. !=1.2.x, is left to the
+				// library, which does not let prereleases through.
+				ok, errs := mustParseConstraints(s).Validate(v)
+				return ok, errs
+			}
+
+			cmp := v.Compare(bound)
+			switch m[1] {
+			case ">=":
+				ok = cmp >= 0
+			case "<=":
+				ok = cmp <= 0
+			case ">":
+				ok = cmp > 0
+			case "<":
+				ok = cmp < 0
+			case "!=":
+				ok = cmp != 0
+			default:
+				ok = cmp == 0
+			}
+
+			if !ok {
+				msgs = append(msgs, fmt.Errorf("%s is not %s", v, term))
+				break
+			}
+		}
+
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, msgs
+}
+
+// negatedOperator maps each comparison to the one matching the other versions.
+var negatedOperator = map[string]string{
+	">=": "<",
+	"<=": ">",
+	">":  "<=",
+	"<":  ">=",
+	"!=": "=",
+	"=":  "!=",
+}
+
+// negateConstraints returns a constraint which matches exactly the versions
+// the constraint s does not match, by semver precedence. s is expanded as by
+// expandConstraints; the negation of its alternatives, each a list of
+// comparisons, is again brought into that form. Combinations which no
+// version can satisfy are left out, and of the others only the tightest
+// bounds are kept.
+func negateConstraints(s string) (string, error) {
+	combos := [][]string{{}}
+	for _, group := range strings.Split(expandConstraints(s), " || ") {
+		// Not all of the comparisons hold, so one of them is negated.
+		next := [][]string{}
+		for _, combo := range combos {
+			for _, term := range strings.Split(group, ", ") {
+				m := comparisonTerm.FindStringSubmatch(term)
+				op := m[1]
+				if op == "" {
+					op = "="
+				}
+
+				negated, ok := tightenBounds(append(append([]string{}, combo...), negatedOperator[op]+m[2]))
+				if ok {
+					next = append(next, negated)
+				}
+			}
+		}
+		combos = next
+	}
+
+	if len(combos) == 0 {
+		return "", fmt.Errorf("'%s' matches every version, so its negation matches none", s)
+	}
+
+	groups := []string{}
+	for _, combo := range combos {
+		groups = append(groups, strings.Join(combo, ", "))
+	}
+
+	return strings.Join(groups, " || "), nil
+}
+
+// tightenBounds keeps only the tightest lower and upper bound of the
+// comparisons, and reports whether any version lies within them. != is
+// assumed to leave some version, and what is not a plain version, e.g. a
+// wildcard, is kept as it is.
+func tightenBounds(terms []string) ([]string, bool) {
+	var lower, upper *semver.Version
+	lowerTerm, upperTerm := "", ""
+	rest := []string{}
+
+	for _, term := range terms {
+		m := comparisonTerm.FindStringSubmatch(term)
+		v, err := semver.NewVersion(m[2])
+		if err != nil || m[1] == "!=" {
+			rest = append(rest, term)
+			continue
+		}
+
+		if m[1] == "" || m[1] == "=" {
+			rest = append(rest, term)
+		}
+		if m[1] != "<" && m[1] != "<=" {
+			if lower == nil || v.Compare(lower) > 0 || (v.Compare(lower) == 0 && m[1] == ">") {
+				lower, lowerTerm = v, term
+			}
+		}
+		if m[1] != ">" && m[1] != ">=" {
+			if upper == nil || v.Compare(upper) < 0 || (v.Compare(upper) == 0 && m[1] == "<") {
+				upper, upperTerm = v, term
+			}
+		}
+	}
+
+	tightened := []string{}
+	for _, term := range []string{lowerTerm, upperTerm} {
+		m := comparisonTerm.FindStringSubmatch(term)
+		if term != "" && m[1] != "" && m[1] != "=" {
+			tightened = append(tightened, term)
+		}
+	}
+	tightened = append(tightened, rest...)
+
+	if upper != nil && strings.HasPrefix(upperTerm, "<") && !strings.HasPrefix(upperTerm, "<=") && upper.Equal(semver.New(0, 0, 0, "", "")) {
+		return tightened, false
+	}
+	if lower == nil || upper == nil {
+		return tightened, true
+	}
+
+	c := lower.Compare(upper)
+	lowerOpen := strings.HasPrefix(lowerTerm, ">") && !strings.HasPrefix(lowerTerm, ">=")
+	upperOpen := strings.HasPrefix(upperTerm, "<") && !strings.HasPrefix(upperTerm, "<=")
+	return tightened, c < 0 || (c == 0 && !lowerOpen && !upperOpen)
+}
@@ -6,4 +6,5 @@ require (
 	github.com/Masterminds/semver/v3 v3.2.0
 	github.com/alecthomas/kingpin/v2 v2.3.1
 	github.com/alecthomas/units v0.0.0-20211218093645-b94a6e3cc137 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
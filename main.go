@@ -1,10 +1,19 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
+	"text/template"
 
 	semver "github.com/Masterminds/semver/v3"
 	kingpin "github.com/alecthomas/kingpin/v2"
@@ -12,218 +21,2386 @@ import (
 
 var version = "1.0.0"
 
+// bumpCommitsFileSet tells an omitted --commits-file apart from a lone '-',
+// which kingpin hands over as an empty value.
+var bumpCommitsFileSet bool
+
+// setArgs counts the arguments given after the COMPONENT of set, because an
+// empty VALUE cannot be told apart from a missing one otherwise.
+var setArgs int
+
+// getSegmentSet tells an omitted --segment apart from --segment 0.
+var getSegmentSet bool
+
+// emptyExitSet tells an omitted --empty-exit-code apart from one set to the
+// error exit code.
+var emptyExitSet bool
+
 var (
-	app     = kingpin.New("semver", "Command-line semver tools. On error, print to stderr and exit -1.")
-	verbose = app.Flag("verbose", "Verbose mode.").Short('v').Bool()
-
-	satisfies            = app.Command("satisfies", "Test if a version satisfies a constraint. Exit 0 if satisfies, 1 if not. If verbose, print an explanation to stdout.")
-	satisfiesVersion     = satisfies.Arg("VERSION", "The version to test").Required().String()
-	satisfiesConstraints = satisfies.Arg("CONSTRAINTS", "The constraints to test against").Required().String()
-
-	greater  = app.Command("greater", "Compare two versions. Exit 0 if the first is greater, 1 if not. If verbose, print greater to stdout.")
-	greaterA = greater.Arg("A", "Left side of A > B").Required().String()
-	greaterB = greater.Arg("B", "Right side of A > B").Required().String()
-
-	lesser  = app.Command("lesser", "Compare two versions. Exit 0 if the first is lesser, 1 if not. If verbose, print lesser to stdout.")
-	lesserA = lesser.Arg("A", "Left side of A < B").Required().String()
-	lesserB = lesser.Arg("B", "Right side of A < B").Required().String()
-
-	equal  = app.Command("equal", "Compare two versions. Exit 0 if they are equal, 1 if not.")
-	equalA = equal.Arg("A", "Left side of A = B").Required().String()
-	equalB = equal.Arg("B", "Right side of A = B").Required().String()
-
-	inc          = app.Command("inc", "Increment major, minor, or patch component.")
-	incComponent = inc.Arg("COMPONENT", "The component to increment. Possible values: [major, minor, patch]").Required().String()
-	incVersion   = inc.Arg("VERSION", "The version to increment.").Required().String()
-
-	get          = app.Command("get", "Get major, minor, patch, prerelease or metadata component.")
-	getComponent = get.Arg("COMPONENT", "The component to increment. Possible values: [major, minor, patch, prerelease, metadata]").Required().String()
-	getVersion   = get.Arg("VERSION", "The version to retreive component from.").Required().String()
-
-	set          = app.Command("set", "Set prerelease or metadata component.")
-	setComponent = set.Arg("COMPONENT", "The component to increment. Possible values: [prerelease, metadata]").Required().String()
-	setVersion   = set.Arg("VERSION", "The version of which to set a component.").Required().String()
-	setValue     = set.Arg("VALUE", "The value to set.").Required().String()
-
-	greatest           = app.Command("greatest", "Find the greatest version in a list.")
-	filter_pre_release = greatest.Flag("filte-pre-release", "Ignores all versions with pre-release information before comparison").Short('p').Bool()
-	filter_build       = greatest.Flag("filte-build", "Ignores all versions with build information before comparison").Short('b').Bool()
-	versions           = greatest.Arg("VERSIONS", "The versions to compare.").Required().Strings()
+	app          = kingpin.New("semver", "Command-line semver tools. On error, print to stderr and exit -1, or the code given by --error-exit-code.")
+	verbose      = app.Flag("verbose", "Verbose mode.").Short('v').Bool()
+	quiet        = app.Flag("quiet", "Quiet mode. Print nothing to stdout, only the exit code matters.").Short('q').Bool()
+	jsonOutput   = app.Flag("json", "Print machine-readable JSON instead of plain text.").Bool()
+	yamlOutput   = app.Flag("yaml", "Print YAML instead of plain text, with the same structure as --json.").Bool()
+	nullDelim    = app.Flag("null", "End each line of output with a NUL byte instead of a newline, e.g. for xargs -0. Versions read from stdin are split at NUL bytes too if it contains any, e.g. from find -print0; files given with --file stay line-based.").Short('0').Bool()
+	noNewline    = app.Flag("no-newline", "Do not end the last line of output with a newline.").Bool()
+	stripV       = app.Flag("strip-prefix", "Strip a leading v from all versions before parsing them.").Bool()
+	outputPrefix = app.Flag("output-prefix", "Prepend this string to every printed version, e.g. v to print tags like v1.2.3.").PlaceHolder("PREFIX").String()
+	inputPrefix  = app.Flag("input-prefix", "Strip this prefix from all versions before parsing them, e.g. release- for tags like release-1.2.3.").PlaceHolder("PREFIX").String()
+	falseExit    = app.Flag("false-exit-code", "Exit with this code instead of 1 when a test like satisfies, greater, lesser, equal, validate or between is false. Errors exit with --error-exit-code.").Default("1").PlaceHolder("N").Int()
+	jsonErrors   = app.Flag("json-errors", "Print errors to stderr as JSON objects with error and code fields instead of plain text.").Bool()
+	errorExit    = app.Flag("error-exit-code", "Exit with this code instead of -1 (255) on errors such as an invalid version or constraint. Independent of --false-exit-code; keep them different to tell a false test from an error.").Default("-1").PlaceHolder("N").Int()
+	emptyExit    = app.Flag("empty-exit-code", "Exit with this code, e.g. 2, instead of the --error-exit-code when greatest or least has no versions left after filtering.").PlaceHolder("N").IsSetByUser(&emptyExitSet).Int()
+
+	satisfies              = app.Command("satisfies", "Test if a version satisfies a constraint. Exit 0 if satisfies, 1 if not. If verbose, print an explanation to stdout.")
+	satisfiesExitCodeMap   = satisfies.Flag("exit-code-map", "Override the exit codes, e.g. satisfied=0,unsatisfied=3").PlaceHolder("satisfied=N,unsatisfied=M").String()
+	satisfiesExplain       = satisfies.Flag("explain", "Print an explanation to stdout whether or not the version satisfies").Bool()
+	satisfiesConstraint    = satisfies.Flag("constraint", "An additional constraint to test against. Can be repeated; all of them, including CONSTRAINTS, must be satisfied unless --any is given.").Short('c').PlaceHolder("CONSTRAINTS").Strings()
+	satisfiesConstraintEnv = satisfies.Flag("constraint-env", "Read an additional constraint from this environment variable, treated like one more --constraint").PlaceHolder("VAR").String()
+	satisfiesAny           = satisfies.Flag("any", "Succeed if the version satisfies any one of the constraints instead of all of them.").Bool()
+	satisfiesIncludePre    = satisfies.Flag("include-prerelease", "Let prerelease versions satisfy constraints without prerelease, too, or --pre. Ranges are expanded as by constraint-expand and compared by semver precedence, except that their upper bounds exclude prereleases, as with npm's includePrerelease. So 1.2.3-rc.1 satisfies >=1.0.0 and ^1.0.0, but 2.0.0-rc.1 does not satisfy ^1.0.0, and 1.0.0-rc.1 does not satisfy >=1.0.0.").Bool()
+	satisfiesFromEnv       = satisfies.Flag("from-env", "Read the version from this environment variable instead of the VERSION argument").PlaceHolder("VAR").String()
+	satisfiesFile          = satisfies.Flag("version-file", "Read the version from this file instead of the VERSION argument").PlaceHolder("PATH").String()
+	satisfiesInvert        = satisfies.Flag("invert", "Exit 0 if the version does not satisfy and 1 if it does. --exit-code-map applies to the inverted result").Bool()
+	satisfiesAll           = satisfies.Flag("all", "Batch mode: test a list of versions and succeed only if every one satisfies.").Bool()
+	satisfiesAnyVersion    = satisfies.Flag("any-version", "Batch mode: test a list of versions and succeed if at least one satisfies.").Bool()
+	satisfiesVersion       = satisfies.Arg("VERSION", "The version to test. In batch mode it may be omitted or '-' to read the versions from stdin, e.g. satisfies --all '>=1' < versions.txt").String()
+	satisfiesConstraints   = satisfies.Arg("CONSTRAINTS", "The constraints to test against. Optional if --constraint is given; otherwise treated like one more --constraint.").String()
+
+	greater            = app.Command("greater", "Compare two versions. Exit 0 if the first is greater, 1 if not. If verbose, print greater to stdout.")
+	greaterExitCodeMap = greater.Flag("exit-code-map", "Override the exit codes, e.g. greater=0,not-greater=3").PlaceHolder("greater=N,not-greater=M").String()
+	greaterA           = greater.Arg("A", "Left side of A > B").Required().String()
+	greaterB           = greater.Arg("B", "Right side of A > B").Required().String()
+
+	lesser            = app.Command("lesser", "Compare two versions. Exit 0 if the first is lesser, 1 if not. If verbose, print lesser to stdout.")
+	lesserExitCodeMap = lesser.Flag("exit-code-map", "Override the exit codes, e.g. lesser=0,not-lesser=3").PlaceHolder("lesser=N,not-lesser=M").String()
+	lesserA           = lesser.Arg("A", "Left side of A < B").Required().String()
+	lesserB           = lesser.Arg("B", "Right side of A < B").Required().String()
+
+	equal               = app.Command("equal", "Compare two versions. Exit 0 if they are equal, 1 if not. Build metadata is ignored, as the semver spec says, so 1.2.3+a equals 1.2.3+b.")
+	equalStrictMetadata = equal.Flag("strict-metadata", "Also require the build metadata to be the same").Bool()
+	equalExitCodeMap    = equal.Flag("exit-code-map", "Override the exit codes, e.g. equal=0,not-equal=3").PlaceHolder("equal=N,not-equal=M").String()
+	equalA              = equal.Arg("A", "Left side of A = B").Required().String()
+	equalB              = equal.Arg("B", "Right side of A = B").Required().String()
+
+	inc           = app.Command("inc", "Increment major, minor, patch or prerelease component.")
+	incPrefix     = inc.Flag("prefix", "Prepend this string to the printed version, e.g. v").String()
+	incPreRelease = inc.Flag("pre-release", "Set this prerelease on the incremented version").String()
+	incStripPre   = inc.Flag("strip-prerelease", "Clear the prerelease of the incremented version").Bool()
+	incStripMeta  = inc.Flag("strip-metadata", "Clear the build metadata of the incremented version").Bool()
+	incID         = inc.Flag("id", "With pre, switch to this prerelease label before bumping, e.g. rc.").PlaceHolder("IDENTIFIER").String()
+	incStart      = inc.Flag("start", "With pre, start a prerelease series on the next patch if the version has none.").Bool()
+	incPreFormat  = inc.Flag("pre-release-format", "Render the prerelease from this Go text/template with the counter {{.N}}, e.g. rc.{{.N}}. With pre, N of a matching prerelease is incremented, otherwise a series starts at --start-at, on the next patch if the version has no prerelease. Other components get the prerelease of --start-at.").PlaceHolder("TEMPLATE").String()
+	incTimes      = inc.Flag("times", "Increment this many times, e.g. patch of 1.2.3 three times is 1.2.6").Default("1").PlaceHolder("N").Uint64()
+	incStartAt    = inc.Flag("start-at", "The first counter of --pre-release-format").Default("0").Uint64()
+	incFromEnv    = inc.Flag("from-env", "Read the version from this environment variable instead of the VERSION argument").PlaceHolder("VAR").String()
+	incFile       = inc.Flag("file", "Read the version from this file instead of the VERSION argument. Its leading v is kept unless --prefix is given").Short('f').String()
+	incWrite      = inc.Flag("write", "Write the incremented version back to the --file").Bool()
+	incComponent  = inc.Arg("COMPONENT", "The component to increment. Possible values: [major, minor, patch, prerelease, pre]").Required().HintOptions("major", "minor", "patch", "prerelease", "pre").String()
+	incVersion    = inc.Arg("VERSION", "The version to increment.").String()
+
+	nextPrereleaseCmd     = app.Command("next-prerelease", "Print the next prerelease of a version in the series of a label, e.g. 1.2.3-rc.4 becomes 1.2.3-rc.5. A version without prerelease, or with one of another label, starts the series at 1.2.3-rc.1.")
+	nextPrereleaseLabel   = nextPrereleaseCmd.Flag("label", "The label of the prerelease series").Default("rc").String()
+	nextPrereleasePrefix  = nextPrereleaseCmd.Flag("prefix", "Prepend this string to the printed version, e.g. v").String()
+	nextPrereleaseVersion = nextPrereleaseCmd.Arg("VERSION", "The version to start from.").Required().String()
+
+	promote              = app.Command("promote", "Turn a prerelease into its release by clearing the prerelease, e.g. 1.2.3-rc.4 becomes 1.2.3.")
+	promoteClearMetadata = promote.Flag("clear-metadata", "Also clear the build metadata").Bool()
+	promoteAllowStable   = promote.Flag("allow-stable", "Print a version without prerelease unchanged instead of failing").Bool()
+	promotePrefix        = promote.Flag("prefix", "Prepend this string to the printed version, e.g. v").String()
+	promoteVersion       = promote.Arg("VERSION", "The prerelease to promote.").Required().String()
+
+	isPrerelease        = app.Command("is-prerelease", "Test if a version has a prerelease. Exit 0 if it has, 1 if not. If verbose, print the prerelease to stdout.")
+	isPrereleaseVersion = isPrerelease.Arg("VERSION", "The version to test.").Required().String()
+
+	isStable        = app.Command("is-stable", "Test if a version has no prerelease. Exit 0 if it has none, 1 if it has. If verbose, print the prerelease to stdout.")
+	isStableVersion = isStable.Arg("VERSION", "The version to test.").Required().String()
+
+	hasMetadata        = app.Command("has-metadata", "Test if a version has build metadata. Exit 0 if it has, 1 if not. If verbose, print the metadata to stdout.")
+	hasMetadataVersion = hasMetadata.Arg("VERSION", "The version to test.").Required().String()
+
+	get          = app.Command("get", "Get major, minor, patch, prerelease, metadata or core component. core is MAJOR.MINOR.PATCH, with a leading v if the version has one.")
+	getStripV    = get.Flag("strip-v", "Print core without the leading v of the version").Bool()
+	getSegment   = get.Flag("segment", "For prerelease and metadata, print only the dot-separated segment with this zero-based index").PlaceHolder("N").IsSetByUser(&getSegmentSet).Uint()
+	getAll       = get.Flag("all", "Print all components as a JSON object instead. Pass only the VERSION").Bool()
+	getFromEnv   = get.Flag("from-env", "Read the version from this environment variable instead of the VERSION argument").PlaceHolder("VAR").String()
+	getFile      = get.Flag("file", "Read the version from this file instead of the VERSION argument").Short('f').String()
+	getComponent = get.Arg("COMPONENT", "The component to retrieve, or a comma-separated list of them, each printed on its own line. Possible values: [major, minor, patch, prerelease, metadata, core]").HintOptions("major", "minor", "patch", "prerelease", "metadata", "core").String()
+	getVersion   = get.Arg("VERSION", "The version to retreive component from.").String()
+
+	set          = app.Command("set", "Set major, minor, patch, prerelease or metadata component.")
+	setPrefix    = set.Flag("prefix", "Prepend this string to the printed version, e.g. v").String()
+	setFromEnv   = set.Flag("from-env", "Read the version from this environment variable instead of the VERSION argument. VALUE follows COMPONENT then").PlaceHolder("VAR").String()
+	setFile      = set.Flag("file", "Read the version from this file instead of the VERSION argument. VALUE follows COMPONENT then. Its leading v is kept unless --prefix is given").Short('f').String()
+	setWrite     = set.Flag("write", "Write the new version back to the --file").Bool()
+	setComponent = set.Arg("COMPONENT", "The component to set. Possible values: [major, minor, patch, prerelease, metadata]").Required().HintOptions("major", "minor", "patch", "prerelease", "metadata").String()
+	setVersion   = set.Arg("VERSION", "The version of which to set a component.").Action(countArg(&setArgs)).String()
+	setValue     = set.Arg("VALUE", "The value to set. For major, minor and patch a non-negative integer; otherwise an empty value clears the component.").Action(countArg(&setArgs)).String()
+
+	greatest             = app.Command("greatest", "Find the greatest version in a list.")
+	filter_pre_release   = greatest.Flag("filter-pre-release", "Ignores all versions with pre-release information before comparison").Short('p').Bool()
+	filter_build         = greatest.Flag("filter-build", "Ignores all versions with build information before comparison").Short('b').Bool()
+	greatestSatisfies    = greatest.Flag("satisfies", "Ignores all versions which do not satisfy these constraints. Can be repeated; all of them must be satisfied").Short('s').PlaceHolder("CONSTRAINTS").Strings()
+	greatestIgnore       = greatest.Flag("ignore-invalid", "Skip versions which fail to parse instead of failing. If verbose, report them on stderr").Bool()
+	greatestGitTags      = greatest.Flag("from-git-tags", "Also read the tags of the git repository in the working directory, skipping those which are not versions").Bool()
+	greatestFile         = greatest.Flag("file", "Also read versions from this file, one per line").Short('f').String()
+	greatestN            = greatest.Flag("n", "Print the N greatest versions in descending order, one per line. Fewer are printed if the list is shorter. --limit is the same").PlaceHolder("N").Uint()
+	greatestGroupByMajor = greatest.Flag("group-by-major", "Print the greatest version of each major version, one per line in ascending order").Bool()
+	greatestDelimiter    = greatest.Flag("delimiter", "Join the versions with this string instead of printing one per line, e.g. ,. Only with --n").Short('d').String()
+	versions             = greatest.Arg("VERSIONS", "The versions to compare. Read from stdin if omitted or '-'.").Strings()
+
+	least                 = app.Command("least", "Find the least version in a list.").Alias("smallest")
+	leastFilterPreRelease = least.Flag("filter-pre-release", "Ignores all versions with pre-release information before comparison").Short('p').Bool()
+	leastFilterBuild      = least.Flag("filter-build", "Ignores all versions with build information before comparison").Short('b').Bool()
+	leastSatisfies        = least.Flag("satisfies", "Ignores all versions which do not satisfy these constraints. Can be repeated; all of them must be satisfied").Short('s').PlaceHolder("CONSTRAINTS").Strings()
+	leastIgnore           = least.Flag("ignore-invalid", "Skip versions which fail to parse instead of failing. If verbose, report them on stderr").Bool()
+	leastGitTags          = least.Flag("from-git-tags", "Also read the tags of the git repository in the working directory, skipping those which are not versions").Bool()
+	leastFile             = least.Flag("file", "Also read versions from this file, one per line").Short('f').String()
+	leastVersions         = least.Arg("VERSIONS", "The versions to compare. Read from stdin if omitted or '-'.").Strings()
+
+	sortCmd              = app.Command("sort", "Sort a list of versions in ascending order and print them one per line.")
+	sortReverse          = sortCmd.Flag("reverse", "Sort in descending order instead").Short('r').Bool()
+	sortUnique           = sortCmd.Flag("unique", "Print versions which are equal only once").Short('u').Bool()
+	sortFilterPreRelease = sortCmd.Flag("filter-pre-release", "Ignores all versions with pre-release information before sorting").Short('p').Bool()
+	sortFilterBuild      = sortCmd.Flag("filter-build", "Ignores all versions with build information before sorting").Short('b').Bool()
+	sortIgnore           = sortCmd.Flag("ignore-invalid", "Skip versions which fail to parse instead of failing. If verbose, report them on stderr").Bool()
+	sortGitTags          = sortCmd.Flag("from-git-tags", "Also read the tags of the git repository in the working directory, skipping those which are not versions").Bool()
+	sortFile             = sortCmd.Flag("file", "Also read versions from this file, one per line").Short('f').String()
+	sortHead             = sortCmd.Flag("head", "Print only the first N versions of the sorted list").PlaceHolder("N").Uint()
+	sortTail             = sortCmd.Flag("tail", "Print only the last N versions of the sorted list").PlaceHolder("N").Uint()
+	sortStableFirst      = sortCmd.Flag("stable-first", "List a release before the pre-releases with the same major, minor and patch, e.g. 1.2.3 before 1.2.3-rc.1").Bool()
+	sortDelimiter        = sortCmd.Flag("delimiter", "Join the versions with this string instead of printing one per line, e.g. ,").Short('d').String()
+	sortVersionList      = sortCmd.Arg("VERSIONS", "The versions to sort. Read from stdin if omitted or '-'.").Strings()
+
+	filter            = app.Command("filter", "Print all versions of a list which satisfy a constraint, one per line.")
+	filterInvert      = filter.Flag("invert", "Print the versions which do not satisfy the constraint instead").Short('i').Bool()
+	filterCount       = filter.Flag("count", "Print the number of matching versions instead of the versions").Short('c').Bool()
+	filterSort        = filter.Flag("sort", "Print the versions in ascending order instead of the input order").Short('s').Bool()
+	filterIgnore      = filter.Flag("ignore-invalid", "Skip versions which fail to parse instead of failing. If verbose, report them on stderr").Bool()
+	filterGitTags     = filter.Flag("from-git-tags", "Also read the tags of the git repository in the working directory, skipping those which are not versions").Bool()
+	filterFile        = filter.Flag("file", "Also read versions from this file, one per line").Short('f').String()
+	filterDelimiter   = filter.Flag("delimiter", "Join the versions with this string instead of printing one per line, e.g. ,").Short('d').String()
+	filterConstraints = filter.Arg("CONSTRAINTS", "The constraints to test against").Required().String()
+	filterVersions    = filter.Arg("VERSIONS", "The versions to filter. Read from stdin if omitted or '-'.").Strings()
+
+	validate            = app.Command("validate", "Test if a version is valid. Exit 0 if valid, 1 if not. If verbose, print the normalized version or the parse error to stdout.").Alias("valid")
+	validateExitCodeMap = validate.Flag("exit-code-map", "Override the exit codes, e.g. valid=0,invalid=3").PlaceHolder("valid=N,invalid=M").String()
+	validateVersion     = validate.Arg("VERSION", "The version to test").Required().String()
+
+	validateConstraint            = app.Command("validate-constraint", "Test if a constraint is valid. Exit 0 if valid, 1 if not. If verbose, print the parse error to stdout.")
+	validateConstraintConstraints = validateConstraint.Arg("CONSTRAINTS", "The constraints to test").Required().String()
+
+	coerce        = app.Command("coerce", "Coerce a loose version like 1.2 or v1.2.3 to strict semver and print it. If verbose, print the original version first.").Alias("normalize")
+	coerceStrict  = coerce.Flag("strict", "Fail if the version requires any coercion").Bool()
+	coercePrefix  = coerce.Flag("prefix", "Prepend this string to the printed version, e.g. v").String()
+	coerceKeepV   = coerce.Flag("keep-v", "Keep a leading v of the original version").Bool()
+	coerceVersion = coerce.Arg("VERSION", "The version to coerce.").Required().String()
+
+	diff         = app.Command("diff", "Print each component which differs between two versions as 'component: old -> new', followed by the kind of change: MAJOR, MINOR, PATCH, PRE-RELEASE, METADATA or NONE.")
+	diffBrief    = diff.Flag("brief", "Only print the most significant component which differs, or equal").Bool()
+	diffExitCode = diff.Flag("exit-code", "Exit with the kind of change: 0 for none, 1 for patch, pre-release or metadata, 2 for minor, 3 for major").Bool()
+	diffA        = diff.Arg("A", "The old version").Required().String()
+	diffB        = diff.Arg("B", "The new version").Required().String()
+
+	compare        = app.Command("compare", "Compare two versions. Print <, = or > to stdout and exit 0. Given more than two versions, or --desc, test instead that they are strictly increasing: exit 0 if so, 1 if not. If verbose, print the first pair out of order.")
+	compareNumeric = compare.Flag("numeric", "Print -1, 0 or 1 instead").Short('n').Bool()
+	compareDesc    = compare.Flag("desc", "Test that the versions are strictly decreasing instead").Bool()
+	compareFile    = compare.Flag("version-file", "Read A from this file instead; the arguments start with B then").PlaceHolder("PATH").String()
+	compareA       = compare.Arg("A", "Left side of the comparison").String()
+	compareB       = compare.Arg("B", "Right side of the comparison").String()
+	compareMore    = compare.Arg("VERSIONS", "More versions to test the order of, after A and B").Strings()
+
+	checkSorted         = app.Command("check-sorted", "Test if a list of versions is in ascending order, where equal versions may follow each other. Exit 0 if so, 1 if not. If verbose, print the first pair out of order.")
+	checkSortedStrict   = checkSorted.Flag("strict", "Require strictly ascending order, without equal neighbors").Bool()
+	checkSortedIgnore   = checkSorted.Flag("ignore-invalid", "Skip versions which fail to parse instead of failing. If verbose, report them on stderr").Bool()
+	checkSortedFile     = checkSorted.Flag("file", "Also read versions from this file, one per line").Short('f').String()
+	checkSortedVersions = checkSorted.Arg("VERSIONS", "The versions to test. Read from stdin if omitted or '-'.").Strings()
+
+	between             = app.Command("between", "Test if a version is in a range. Exit 0 if MIN <= VERSION <= MAX, 1 if not. If verbose, print the violated bound to stdout.")
+	betweenExitCodeMap  = between.Flag("exit-code-map", "Override the exit codes, e.g. between=0,not-between=3").PlaceHolder("between=N,not-between=M").String()
+	betweenExclusiveMin = between.Flag("exclusive-min", "Do not accept a version equal to MIN").Bool()
+	betweenExclusiveMax = between.Flag("exclusive-max", "Do not accept a version equal to MAX").Bool()
+	betweenVersion      = between.Arg("VERSION", "The version to test").Required().String()
+	betweenMin          = between.Arg("MIN", "The lower bound").Required().String()
+	betweenMax          = between.Arg("MAX", "The upper bound").Required().String()
+
+	strip          = app.Command("strip", "Remove prerelease and/or metadata component.")
+	stripPrefix    = strip.Flag("prefix", "Prepend this string to the printed version, e.g. v").String()
+	stripComponent = strip.Arg("COMPONENT", "The component to remove. Possible values: [prerelease, metadata, all]").Required().HintOptions("prerelease", "metadata", "all").String()
+	stripVersion   = strip.Arg("VERSION", "The version to strip.").Required().String()
+
+	format            = app.Command("format", "Print a version using a Go text/template. The fields Major, Minor, Patch, Prerelease, Metadata and Original are available.")
+	formatTemplate    = format.Flag("template", "The template to render. Takes the place of the TEMPLATE argument").Short('t').String()
+	formatTemplateArg = format.Arg("TEMPLATE", "The template to render, e.g. '{{.Major}}.{{.Minor}}'. Omit it if --template is given.").String()
+	formatVersion     = format.Arg("VERSION", "The version to format.").String()
+
+	parse        = app.Command("parse", "Print all components of a version as component=value pairs, one per line.")
+	parseFormat  = parse.Flag("format", "The output format. Possible values: [lines, env, json]").Default("lines").Enum("lines", "env", "json")
+	parseVersion = parse.Arg("VERSION", "The version to parse.").Required().String()
+
+	export        = app.Command("export", "Print all components of a version as shell variable assignments, e.g. for eval \"$(semver export 1.2.3)\". The same as parse --format env, with a configurable prefix.")
+	exportPrefix  = export.Flag("prefix", "Prepend this string to the upper-case component names").Default("SEMVER_").String()
+	exportVersion = export.Arg("VERSION", "The version to export.").Required().String()
+
+	unique            = app.Command("unique", "Print each distinct version of a list once, in the order of their first occurrence.")
+	uniqueSort        = unique.Flag("sort", "Print the versions in ascending order instead").Short('s').Bool()
+	uniqueCount       = unique.Flag("count", "Print the number of distinct versions instead").Short('c').Bool()
+	uniqueIgnore      = unique.Flag("ignore-invalid", "Skip versions which fail to parse instead of failing. If verbose, report them on stderr").Bool()
+	uniqueFile        = unique.Flag("file", "Also read versions from this file, one per line").Short('f').String()
+	uniqueDelimiter   = unique.Flag("delimiter", "Join the versions with this string instead of printing one per line, e.g. ,").Short('d').String()
+	uniqueVersionList = unique.Arg("VERSIONS", "The versions to deduplicate. Read from stdin if omitted or '-'.").Strings()
+
+	dedup             = app.Command("dedup", "Print each distinct version of a list once, in ascending order. Versions are distinct if they are not equal by semver precedence, so 1.2.3 and v1.2.3 are the same.")
+	dedupKeepOriginal = dedup.Flag("keep-original", "Print the first seen spelling of each version instead of the normalized one").Bool()
+	dedupIgnore       = dedup.Flag("ignore-invalid", "Skip versions which fail to parse instead of failing. If verbose, report them on stderr").Bool()
+	dedupFile         = dedup.Flag("file", "Also read versions from this file, one per line").Short('f').String()
+	dedupDelimiter    = dedup.Flag("delimiter", "Join the versions with this string instead of printing one per line, e.g. ,").Short('d').String()
+	dedupVersions     = dedup.Arg("VERSIONS", "The versions to deduplicate. Read from stdin if omitted or '-'.").Strings()
+
+	rangeCmd       = app.Command("range", "Print every version from A to B inclusive, by default every patch. Like sequence with the component as flag.")
+	rangeBy        = rangeCmd.Flag("by", "The component to step by. Possible values: [major, minor, patch]").Default("patch").Enum("major", "minor", "patch")
+	rangeDelimiter = rangeCmd.Flag("delimiter", "Join the versions with this string instead of printing one per line, e.g. ,").Short('d').String()
+	rangeA         = rangeCmd.Arg("A", "The first version").Required().String()
+	rangeB         = rangeCmd.Arg("B", "The last version").Required().String()
+
+	completion      = app.Command("completion", "Print a shell completion script. Source it in your shell profile or write it to /etc/bash_completion.d/. Commands and component names are completed.").Alias("generate-completion")
+	completionShell = completion.Arg("SHELL", "The shell to complete in. Possible values: [bash, zsh, fish, powershell]").Required().Enum("bash", "zsh", "fish", "powershell")
+
+	next          = app.Command("next", "Print the next version. Increment the component, reset all lower ones and drop prerelease and metadata. pre bumps the prerelease counter, or starts a prerelease of the next patch. Like npm version prerelease, a new series starts at 0, e.g. 1.2.4-0 or 1.2.4-rc.0, unlike next-prerelease and inc pre --start which start at 1.")
+	nextPrefix    = next.Flag("prefix", "Prepend this string to the printed version, e.g. v").String()
+	nextPreID     = next.Flag("pre-id", "The prerelease identifier to append, e.g. beta.0 or rc").String()
+	nextComponent = next.Arg("COMPONENT", "The component to increment. Possible values: [major, minor, patch, pre]").Required().HintOptions("major", "minor", "patch", "pre").String()
+	nextVersion   = next.Arg("VERSION", "The version to start from.").Required().String()
+
+	clamp         = app.Command("clamp", "Restrict a version to a range. Print MIN if the version is below it, MAX if it is above it and the version itself otherwise.")
+	clampExitCode = clamp.Flag("exit-code", "Exit 1 if the version was clamped, 0 if it was in range").Bool()
+	clampVersion  = clamp.Arg("VERSION", "The version to clamp").Required().String()
+	clampMin      = clamp.Arg("MIN", "The lower bound").Required().String()
+	clampMax      = clamp.Arg("MAX", "The upper bound").Required().String()
+
+	count                 = app.Command("count", "Print the number of versions in a list. Constraints are passed with --satisfies; every argument is a version.")
+	countSatisfies        = count.Flag("satisfies", "Only count versions which satisfy these constraints. Can be repeated; all of them must be satisfied").Short('s').PlaceHolder("CONSTRAINTS").Strings()
+	countFilterPreRelease = count.Flag("filter-pre-release", "Ignores all versions with pre-release information").Short('p').Bool()
+	countFilterBuild      = count.Flag("filter-build", "Ignores all versions with build information").Short('b').Bool()
+	countIgnore           = count.Flag("ignore-invalid", "Skip versions which fail to parse instead of failing. If verbose, report them on stderr").Bool()
+	countFile             = count.Flag("file", "Also read versions from this file, one per line").Short('f').String()
+	countExitIfEmpty      = count.Flag("exit-nonzero-if-empty", "Exit 1 instead of printing 0 if no version is counted").Bool()
+	countVersions         = count.Arg("VERSIONS", "The versions to count. Read from stdin if omitted or '-'.").Strings()
+
+	bump            = app.Command("bump", "Increment a version like inc. With --level=auto the level is derived from conventional commit messages: feat bumps minor, fix bumps patch and a breaking change bumps major.")
+	bumpLevel       = bump.Flag("level", "The level to increment. Possible values: [major, minor, patch, auto]").Default("patch").Enum("major", "minor", "patch", "auto")
+	bumpCommitsFile = bump.Flag("commits-file", "Read the commit messages for --level=auto from this file, - for stdin. Separate full messages with NUL bytes, as git log -z --format=%B does, or pass the output of git log; otherwise each line is taken as the header of a message.").PlaceHolder("FILE").IsSetByUser(&bumpCommitsFileSet).String()
+	bumpPrefix      = bump.Flag("prefix", "Prepend this string to the printed version, e.g. v").String()
+	bumpVersion     = bump.Arg("VERSION", "The version to increment.").Required().String()
+
+	maxSatisfying            = app.Command("max-satisfying", "Print the greatest version in a list which satisfies a constraint. Exit 1 if none does.")
+	maxSatisfyingConstraints = maxSatisfying.Arg("CONSTRAINTS", "The constraints to test against").Required().String()
+	maxSatisfyingVersions    = maxSatisfying.Arg("VERSIONS", "The versions to choose from. Read from stdin if omitted or '-'.").Strings()
+
+	latestStable         = app.Command("latest-stable", "Find the greatest version in a list without prerelease and build information, like greatest -p -b. Exit 1 if there is none.")
+	latestStableIgnore   = latestStable.Flag("ignore-invalid", "Skip versions which fail to parse instead of failing. If verbose, report them on stderr").Bool()
+	latestStableFile     = latestStable.Flag("file", "Also read versions from this file, one per line").Short('f').String()
+	latestStableVersions = latestStable.Arg("VERSIONS", "The versions to compare. Read from stdin if omitted or '-'.").Strings()
+
+	update          = app.Command("update", "Increment the version stored in a file, write it back and print it.")
+	updateFile      = update.Flag("file", "The file holding the version").Short('f').Required().String()
+	updatePrefix    = update.Flag("prefix", "Prepend this string to the written and printed version, e.g. v. Without it, a leading v of the file is kept").String()
+	updateComponent = update.Arg("COMPONENT", "The component to increment. Possible values: [major, minor, patch, prerelease]").Required().HintOptions("major", "minor", "patch", "prerelease").String()
+
+	matchesAny                = app.Command("matches-any", "Test if a version satisfies any constraint from a file. Exit 0 if it does, 1 if not. If verbose, print the matching constraint to stdout.")
+	matchesAnyConstraintsFile = matchesAny.Flag("constraints-file", "Read the constraints from this file, one per line. Blank lines and lines starting with # are skipped").Required().PlaceHolder("FILE").String()
+	matchesAnyVersion         = matchesAny.Arg("VERSION", "The version to test").Required().String()
+
+	constraintExpand            = app.Command("constraint-expand", "Print the comparisons a constraint stands for, e.g. >=1.2.3, <2.0.0 for ^1.2.3. Caret, tilde, wildcard and hyphen ranges are expanded.")
+	constraintExpandConstraints = constraintExpand.Arg("CONSTRAINTS", "The constraints to expand").Required().String()
+
+	negate           = app.Command("negate", "Print a constraint which matches exactly the versions a constraint does not match, e.g. <1.2.3 || >=2.0.0 for ^1.2.3. Versions are compared by semver precedence, so prereleases follow the rules of the printed constraint. Fails if the constraint matches every version.")
+	negateConstraint = negate.Arg("CONSTRAINTS", "The constraints to negate").Required().String()
+
+	difference          = app.Command("difference", "Print the versions of list A which are not in list B, e.g. difference 1.0.0 1.1.0 -- 1.0.0.")
+	differenceSort      = difference.Flag("sort", "Print the versions in ascending order instead of the input order").Short('s').Bool()
+	differenceSeparator = difference.Flag("separator", "The argument which separates list A from list B").Default("--").String()
+	differenceDelimiter = difference.Flag("delimiter", "Join the versions with this string instead of printing one per line, e.g. ,").Short('d').String()
+	differenceLists     = difference.Arg("LISTS", "The versions of A, the separator and the versions of B").Required().Strings()
+
+	intersection          = app.Command("intersection", "Print the versions of list A which are also in list B, e.g. intersection 1.0.0 1.1.0 -- 1.0.0.")
+	intersectionSort      = intersection.Flag("sort", "Print the versions in ascending order instead of the input order").Short('s').Bool()
+	intersectionSeparator = intersection.Flag("separator", "The argument which separates list A from list B").Default("--").String()
+	intersectionDelimiter = intersection.Flag("delimiter", "Join the versions with this string instead of printing one per line, e.g. ,").Short('d').String()
+	intersectionLists     = intersection.Arg("LISTS", "The versions of A, the separator and the versions of B").Required().Strings()
+
+	union          = app.Command("union", "Print each version of list A and list B once, e.g. union 1.0.0 -- 1.0.0 1.1.0.")
+	unionSort      = union.Flag("sort", "Print the versions in ascending order instead of the input order").Short('s').Bool()
+	unionSeparator = union.Flag("separator", "The argument which separates list A from list B").Default("--").String()
+	unionDelimiter = union.Flag("delimiter", "Join the versions with this string instead of printing one per line, e.g. ,").Short('d').String()
+	unionLists     = union.Arg("LISTS", "The versions of A, the separator and the versions of B").Required().Strings()
+
+	sequence          = app.Command("sequence", "Print every version from A to B, incrementing the given component, e.g. sequence minor 1.0.0 1.3.0.")
+	sequenceInclusive = sequence.Flag("inclusive", "Include A and B in the output").Default("true").Bool()
+	sequenceExclusive = sequence.Flag("exclusive", "Leave out A and B, same as --no-inclusive").Bool()
+	sequenceDelimiter = sequence.Flag("delimiter", "Join the versions with this string instead of printing one per line, e.g. ,").Short('d').String()
+	sequenceComponent = sequence.Arg("COMPONENT", "The component to increment. Possible values: [major, minor, patch]").Required().Enum("major", "minor", "patch")
+	sequenceA         = sequence.Arg("A", "The first version").Required().String()
+	sequenceB         = sequence.Arg("B", "The last version").Required().String()
 )
 
+func init() {
+	// Keep the previously misspelled flag names working for existing scripts.
+	greatest.Flag("filte-pre-release", "").Hidden().BoolVar(filter_pre_release)
+	greatest.Flag("filte-build", "").Hidden().BoolVar(filter_build)
+
+	// Spellings of the between bounds before they became inclusive by default.
+	between.Flag("exclude-lower", "").Hidden().BoolVar(betweenExclusiveMin)
+	between.Flag("include-upper", "").Hidden().Bool()
+
+	satisfies.Flag("pre", "").Hidden().BoolVar(satisfiesIncludePre)
+	greatest.Flag("limit", "").Hidden().UintVar(greatestN)
+
+	// satisfies and compare call the version file --version-file.
+	inc.Flag("version-file", "").Hidden().StringVar(incFile)
+	get.Flag("version-file", "").Hidden().StringVar(getFile)
+	set.Flag("version-file", "").Hidden().StringVar(setFile)
+}
+
 func main() {
 	kingpin.Version(version)
 
-	switch kingpin.MustParse(app.Parse(os.Args[1:])) {
+	command, err := app.Parse(os.Args[1:])
+	if err != nil {
+		// The flags are not set when parsing fails, so look for the
+		// option on the raw command line.
+		for _, a := range os.Args[1:] {
+			if a == "--json-errors" {
+				printJSONError(err.Error(), 1)
+				os.Exit(1)
+			}
+		}
+	}
+	kingpin.MustParse(command, err)
+
+	if *yamlOutput {
+		if *jsonOutput {
+			fatalf("--json and --yaml are mutually exclusive")
+		}
+
+		// YAML takes the structured output paths of JSON.
+		*jsonOutput = true
+	}
+
+	if *quiet {
+		if *verbose {
+			fatalf("--quiet and --verbose are mutually exclusive")
+		}
+
+		devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err != nil {
+			fatalf("Failed to open %s; %v", os.DevNull, err)
+		}
+		os.Stdout = devNull
+	}
+
+	switch command {
 	case satisfies.FullCommand():
+		codes := mustParseExitCodeMap(*satisfiesExitCodeMap, "satisfied", "unsatisfied")
+
+		if *satisfiesFromEnv != "" || *satisfiesFile != "" {
+			// The version is not passed as argument, so the only one is the
+			// constraint.
+			if *satisfiesConstraints != "" {
+				fatalf("pass the version only once: as argument, with --from-env or with --version-file")
+			}
+			*satisfiesConstraints = *satisfiesVersion
+			*satisfiesVersion = mustVersionArg("", *satisfiesFromEnv, *satisfiesFile)
+		}
+
+		if *satisfiesAll && *satisfiesAnyVersion {
+			fatalf("--all and --any-version are mutually exclusive")
+		}
+
+		if *satisfiesAll || *satisfiesAnyVersion {
+			versionArgs := []string{}
+			if *satisfiesConstraints == "" && len(*satisfiesConstraint) == 0 && *satisfiesConstraintEnv == "" {
+				// Only one positional given: it is the constraint and the
+				// versions come from stdin.
+				*satisfiesConstraints = *satisfiesVersion
+			} else if *satisfiesVersion != "" {
+				versionArgs = append(versionArgs, *satisfiesVersion)
+			}
+
+			raw := satisfiesConstraintArgs()
+			passing, failing := []string{}, []string{}
+			for _, v := range mustParseVersions(readVersionArgs(versionArgs, ""), false) {
+				if ok, _, _ := checkConstraints(&v, raw, *satisfiesAny); ok {
+					passing = append(passing, v.String())
+				} else {
+					failing = append(failing, v.String())
+				}
+			}
+
+			does := len(failing) == 0
+			if *satisfiesAnyVersion {
+				does = len(passing) > 0
+			}
+
+			if *jsonOutput {
+				printJSON(struct {
+					Satisfies bool     `json:"satisfies"`
+					Passing   []string `json:"passing"`
+					Failing   []string `json:"failing"`
+				}{does, passing, failing})
+			} else if *verbose || *satisfiesExplain {
+				for _, v := range passing {
+					printLinef("%s satisfies", v)
+				}
+				for _, v := range failing {
+					printLinef("%s does not satisfy", v)
+				}
+			}
+
+			if does == *satisfiesInvert {
+				exitResult(codes, "unsatisfied")
+			}
+			exitResult(codes, "satisfied")
+		}
+
+		if *satisfiesVersion == "" {
+			fatalf("required argument 'VERSION' not provided")
+		}
+
 		v := mustParseVersion(*satisfiesVersion, "VERSION")
-		c := mustParseConstraints(*satisfiesConstraints)
+		raw := satisfiesConstraintArgs()
+		does, msgs, _ := checkConstraints(v, raw, *satisfiesAny)
 
-		if does, msgs := c.Validate(v); !does {
-			if *verbose {
+		var clauses []clauseResult
+		if *satisfiesExplain {
+			for _, r := range raw {
+				clauses = append(clauses, explainClauses(v, r, *satisfiesIncludePre)...)
+			}
+		}
+
+		if *jsonOutput {
+			messages := []string{}
+			if !does {
 				for _, m := range msgs {
-					fmt.Println(m)
+					messages = append(messages, m.Error())
 				}
 			}
 
-			os.Exit(1)
+			printJSON(struct {
+				Satisfies bool           `json:"satisfies"`
+				Messages  []string       `json:"messages"`
+				Clauses   []clauseResult `json:"clauses,omitempty"`
+			}{does, messages, clauses})
+		} else if *satisfiesExplain {
+			printClauses(clauses)
+
+			verdict := "satisfies"
+			if !does {
+				verdict = "does not satisfy"
+			}
+			printLinef("%s %s %s", v, verdict, strings.Join(raw, " and "))
+		} else if *verbose && *satisfiesInvert && does {
+			printLinef("%s satisfies %s when it should not", v, strings.Join(raw, " and "))
+		} else if *verbose && !*satisfiesInvert && !does {
+			for _, m := range msgs {
+				printLine(m)
+			}
 		}
 
-		os.Exit(0)
+		if does == *satisfiesInvert {
+			exitResult(codes, "unsatisfied")
+		}
+		exitResult(codes, "satisfied")
 
 	case greater.FullCommand():
+		codes := mustParseExitCodeMap(*greaterExitCodeMap, "greater", "not-greater")
 		a := mustParseVersion(*greaterA, "A")
 		b := mustParseVersion(*greaterB, "B")
 
 		if !a.GreaterThan(b) {
-			if *verbose {
-				fmt.Println(*greaterB)
+			if *jsonOutput {
+				printJSON(struct {
+					Greater bool   `json:"greater"`
+					Version string `json:"version"`
+				}{false, *greaterB})
+			} else if *verbose {
+				printLine(*greaterB)
 			}
-			os.Exit(1)
+			exitResult(codes, "not-greater")
 		}
 
-		if *verbose {
-			fmt.Println(*greaterA)
+		if *jsonOutput {
+			printJSON(struct {
+				Greater bool   `json:"greater"`
+				Version string `json:"version"`
+			}{true, *greaterA})
+		} else if *verbose {
+			printLine(*greaterA)
 		}
-		os.Exit(0)
+		exitResult(codes, "greater")
 
 	case lesser.FullCommand():
+		codes := mustParseExitCodeMap(*lesserExitCodeMap, "lesser", "not-lesser")
 		a := mustParseVersion(*lesserA, "A")
 		b := mustParseVersion(*lesserB, "B")
 
 		if !a.LessThan(b) {
-			if *verbose {
-				fmt.Println(*lesserB)
+			if *jsonOutput {
+				printJSON(struct {
+					Lesser  bool   `json:"lesser"`
+					Version string `json:"version"`
+				}{false, *lesserB})
+			} else if *verbose {
+				printLine(*lesserB)
 			}
-			os.Exit(1)
+			exitResult(codes, "not-lesser")
 		}
 
-		if *verbose {
-			fmt.Println(*lesserA)
+		if *jsonOutput {
+			printJSON(struct {
+				Lesser  bool   `json:"lesser"`
+				Version string `json:"version"`
+			}{true, *lesserA})
+		} else if *verbose {
+			printLine(*lesserA)
 		}
-		os.Exit(0)
+		exitResult(codes, "lesser")
 
 	case equal.FullCommand():
+		codes := mustParseExitCodeMap(*equalExitCodeMap, "equal", "not-equal")
 		a := mustParseVersion(*equalA, "A")
 		b := mustParseVersion(*equalB, "B")
 
-		if !a.Equal(b) {
-			os.Exit(1)
+		isEqual := a.Equal(b)
+		if *equalStrictMetadata && a.Metadata() != b.Metadata() {
+			isEqual = false
 		}
 
-		os.Exit(0)
+		if *jsonOutput {
+			printJSON(struct {
+				Equal bool `json:"equal"`
+			}{isEqual})
+		}
+
+		if !isEqual {
+			exitResult(codes, "not-equal")
+		}
+
+		exitResult(codes, "equal")
 
 	case inc.FullCommand():
+		*incVersion = mustVersionArg(*incVersion, *incFromEnv, *incFile)
 		v := mustParseVersion(*incVersion, "VERSION")
+		if *incPreFormat != "" && (*incPreRelease != "" || *incID != "") {
+			fatalf("--pre-release-format cannot be combined with --pre-release or --id")
+		}
+		if *incTimes == 0 {
+			fatalf("--times must be at least 1")
+		}
+
 		var v1 semver.Version
-		switch *incComponent {
-		case "major":
-			v1 = v.IncMajor()
-		case "minor":
-			v1 = v.IncMinor()
-		case "patch":
-			v1 = v.IncPatch()
+		switch {
+		case *incComponent == "pre" && *incPreFormat != "":
+			n, ok := prereleaseCounter(*incPreFormat, v.Prerelease())
+			patch := v.Patch()
+			if ok {
+				n += *incTimes
+			} else {
+				n = *incStartAt + *incTimes - 1
+				if v.Prerelease() == "" {
+					patch++
+				}
+			}
+
+			var err error
+			if v1, err = semver.New(v.Major(), v.Minor(), patch, "", "").SetPrerelease(mustRenderPrerelease(*incPreFormat, n)); err != nil {
+				fatalf("invalid prerelease; %v", err)
+			}
+		case *incComponent == "pre":
+			if *incID != "" {
+				if _, err := v.SetPrerelease(*incID); err != nil {
+					fatalf("invalid prerelease identifier; %v", err)
+				}
+			}
+
+			pre := v.Prerelease()
+			patch := v.Patch()
+			switch {
+			case pre == "" && !*incStart:
+				fatalf("version has no prerelease to increment: '%s'; use --start to begin one", *incVersion)
+			case pre == "":
+				patch++
+				pre = *incID
+			case *incID != "" && pre != *incID && !strings.HasPrefix(pre, *incID+"."):
+				pre = *incID
+			}
+
+			if pre == "" {
+				pre = "1"
+			} else {
+				pre = incPrerelease(pre)
+			}
+			for i := uint64(1); i < *incTimes; i++ {
+				pre = incPrerelease(pre)
+			}
+			v1 = *semver.New(v.Major(), v.Minor(), patch, pre, "")
 		default:
-			fmt.Fprintf(os.Stderr, "unknown component name: '%s'\n", *incComponent)
-			os.Exit(-1)
+			v1 = *v
+			for i := uint64(0); i < *incTimes; i++ {
+				v1 = mustIncrement(&v1, *incComponent)
+			}
+
+			if *incPreFormat != "" {
+				var err error
+				if v1, err = v1.SetPrerelease(mustRenderPrerelease(*incPreFormat, *incStartAt)); err != nil {
+					fatalf("invalid prerelease; %v", err)
+				}
+			}
+		}
+
+		if *incPreRelease != "" {
+			if *incStripPre {
+				fatalf("--pre-release cannot be combined with --strip-prerelease")
+			}
+
+			var err error
+			if v1, err = v1.SetPrerelease(*incPreRelease); err != nil {
+				fatalf("invalid prerelease; %v", err)
+			}
+		}
+		if *incStripPre {
+			v1, _ = v1.SetPrerelease("")
+		}
+		if *incStripMeta {
+			v1, _ = v1.SetMetadata("")
+		}
+		prefix := versionFilePrefix(*incPrefix, *incFile, *incVersion)
+		if *incWrite {
+			writeVersionFile(*incFile, prefix+v1.String())
+		}
+		printVersion(&v1, prefix)
+
+	case nextPrereleaseCmd.FullCommand():
+		v := mustParseVersion(*nextPrereleaseVersion, "VERSION")
+		label := *nextPrereleaseLabel
+
+		pre := label + ".1"
+		if v.Prerelease() == label || strings.HasPrefix(v.Prerelease(), label+".") {
+			pre = incPrerelease(v.Prerelease())
+		}
+
+		v1, err := semver.New(v.Major(), v.Minor(), v.Patch(), "", "").SetPrerelease(pre)
+		if err != nil {
+			fatalf("invalid prerelease label; %v", err)
+		}
+		printVersion(&v1, *nextPrereleasePrefix)
+
+	case promote.FullCommand():
+		v := mustParseVersion(*promoteVersion, "VERSION")
+
+		if v.Prerelease() == "" && !*promoteAllowStable {
+			fatalf("nothing to promote, version has no prerelease: '%s'", *promoteVersion)
+		}
+
+		v1, _ := v.SetPrerelease("")
+		if *promoteClearMetadata {
+			v1, _ = v1.SetMetadata("")
+		}
+		printVersion(&v1, *promotePrefix)
+
+	case isPrerelease.FullCommand():
+		pre := mustParseVersion(*isPrereleaseVersion, "VERSION").Prerelease()
+		printPrerelease(pre)
+
+		if pre == "" {
+			exitFalse()
+		}
+
+	case isStable.FullCommand():
+		pre := mustParseVersion(*isStableVersion, "VERSION").Prerelease()
+		printPrerelease(pre)
+
+		if pre != "" {
+			exitFalse()
+		}
+
+	case hasMetadata.FullCommand():
+		metadata := mustParseVersion(*hasMetadataVersion, "VERSION").Metadata()
+
+		if *jsonOutput {
+			printJSON(struct {
+				HasMetadata bool   `json:"has_metadata"`
+				Metadata    string `json:"metadata"`
+			}{metadata != "", metadata})
+		} else if *verbose && metadata != "" {
+			printLine(metadata)
+		}
+
+		if metadata == "" {
+			exitFalse()
 		}
-		fmt.Println(v1.String())
 
 	case get.FullCommand():
-		v := mustParseVersion(*getVersion, "VERSION")
-		var component string
-		switch *getComponent {
-		case "major":
-			component = strconv.FormatUint(v.Major(), 10)
-		case "minor":
-			component = strconv.FormatUint(v.Minor(), 10)
-		case "patch":
-			component = strconv.FormatUint(v.Patch(), 10)
-		case "prerelease":
-			component = v.Prerelease()
-		case "metadata":
-			component = v.Metadata()
+		if *getAll {
+			// With --all the only argument is the version.
+			if *getVersion != "" {
+				fatalf("--all cannot be combined with a COMPONENT")
+			}
+
+			v := mustParseVersion(mustVersionArg(*getComponent, *getFromEnv, *getFile), "VERSION")
+			printJSON(struct {
+				Major      uint64 `json:"major"`
+				Minor      uint64 `json:"minor"`
+				Patch      uint64 `json:"patch"`
+				Prerelease string `json:"prerelease"`
+				Metadata   string `json:"metadata"`
+			}{v.Major(), v.Minor(), v.Patch(), v.Prerelease(), v.Metadata()})
+			break
+		}
+
+		if *getComponent == "" {
+			fatalf("required argument 'COMPONENT' not provided")
+		}
+
+		v := mustParseVersion(mustVersionArg(*getVersion, *getFromEnv, *getFile), "VERSION")
+
+		type getResult struct {
+			Component string `json:"component"`
+			Value     string `json:"value"`
+		}
+		results := []getResult{}
+		for _, name := range strings.Split(*getComponent, ",") {
+			component, ok := componentValue(v, name)
+			if !ok {
+				fatalf("unknown component name: '%s'", name)
+			}
+
+			// Only core carries the leading v of the version.
+			if *getStripV && name == "core" {
+				component = strings.TrimPrefix(component, "v")
+			}
+
+			if getSegmentSet {
+				if name != "prerelease" && name != "metadata" {
+					fatalf("--segment only applies to prerelease and metadata, not '%s'", name)
+				}
+
+				segments := strings.Split(component, ".")
+				if component == "" || *getSegment >= uint(len(segments)) {
+					fatalf("%s has no segment %d: '%s'", name, *getSegment, component)
+				}
+				component = segments[*getSegment]
+			}
+			results = append(results, getResult{name, component})
+		}
+
+		switch {
+		case *yamlOutput && len(results) == 1:
+			// Print major, minor and patch as numbers, not quoted strings.
+			switch results[0].Component {
+			case "major":
+				printJSON(v.Major())
+			case "minor":
+				printJSON(v.Minor())
+			case "patch":
+				printJSON(v.Patch())
+			default:
+				printJSON(results[0].Value)
+			}
+		case *jsonOutput && len(results) == 1:
+			printJSON(results[0])
+		case *jsonOutput:
+			printJSON(struct {
+				Components []getResult `json:"components"`
+			}{results})
 		default:
-			fmt.Fprintf(os.Stderr, "unknown component name: '%s'\n", *getComponent)
-			os.Exit(-1)
+			for _, r := range results {
+				printLine(r.Value)
+			}
 		}
-		fmt.Println(component)
 
 	case set.FullCommand():
+		if *setFromEnv != "" || *setFile != "" {
+			// The version is not passed as argument, so the first one after
+			// COMPONENT is the value.
+			if setArgs > 1 {
+				fatalf("pass the version only once: as argument, with --from-env or with --file")
+			}
+			*setValue = *setVersion
+			*setVersion = mustVersionArg("", *setFromEnv, *setFile)
+			setArgs++
+		}
+
+		if setArgs < 2 {
+			fatalf("required argument 'VALUE' not provided")
+		}
+
 		v := mustParseVersion(*setVersion, "VERSION")
 		var v1 semver.Version
 		var err error
 		switch *setComponent {
+		case "major":
+			v1 = *semver.New(mustParseNumber(*setValue, "major"), v.Minor(), v.Patch(), v.Prerelease(), v.Metadata())
+		case "minor":
+			v1 = *semver.New(v.Major(), mustParseNumber(*setValue, "minor"), v.Patch(), v.Prerelease(), v.Metadata())
+		case "patch":
+			v1 = *semver.New(v.Major(), v.Minor(), mustParseNumber(*setValue, "patch"), v.Prerelease(), v.Metadata())
 		case "prerelease":
 			if v1, err = v.SetPrerelease(*setValue); err != nil {
-				fmt.Fprintf(os.Stderr, "invalid prerelease; %v\n", err)
-				os.Exit(-1)
+				fatalf("invalid prerelease; %v", err)
 			}
 		case "metadata":
 			if v1, err = v.SetMetadata(*setValue); err != nil {
-				fmt.Fprintf(os.Stderr, "invalid metadata; %v\n", err)
-				os.Exit(-1)
+				fatalf("invalid metadata; %v", err)
 			}
 		default:
-			fmt.Fprintf(os.Stderr, "unknown component name: '%s'\n", *setComponent)
-			os.Exit(-1)
+			fatalf("unknown component name: '%s'", *setComponent)
 		}
-		fmt.Println(v1.String())
+		prefix := versionFilePrefix(*setPrefix, *setFile, *setVersion)
+		if *setWrite {
+			writeVersionFile(*setFile, prefix+v1.String())
+		}
+		printVersion(&v1, prefix)
 
 	case greatest.FullCommand():
-		all_parsed_versions := []semver.Version{}
-		for _, v := range *versions {
-			all_parsed_versions = append(all_parsed_versions, *mustParseVersion(v, "VERSION"))
+		rawVersions := readVersionSources(*versions, *greatestFile, *greatestGitTags)
+		filtered_versions := filterSatisfying(filterAndSortVersions(mustParseVersions(rawVersions, *greatestIgnore), *filter_pre_release, *filter_build), *greatestSatisfies)
+
+		if len(filtered_versions) == 0 {
+			failf(emptyExitCode(), "no versions remain after filtering")
 		}
 
-		filtered_versions := all_parsed_versions
+		if *greatestGroupByMajor {
+			if *greatestN > 0 {
+				fatalf("--group-by-major and --n are mutually exclusive")
+			}
 
-		if *filter_pre_release {
-			filtered_pre_release := []semver.Version{}
-			for _, v := range all_parsed_versions {
-				if v.Prerelease() == "" {
-					filtered_pre_release = append(filtered_pre_release, v)
+			// The versions are sorted, so the last one of each major wins.
+			for i, v := range filtered_versions {
+				if i+1 < len(filtered_versions) && filtered_versions[i+1].Major() == v.Major() {
+					continue
+				}
+
+				if *jsonOutput {
+					printJSON(struct {
+						Major    uint64 `json:"major"`
+						Greatest string `json:"greatest"`
+					}{v.Major(), v.String()})
+				} else {
+					printLine(*outputPrefix + v.String())
 				}
 			}
-			filtered_versions = filtered_pre_release
+			break
 		}
 
-		if *filter_build {
-			filtered_build := []semver.Version{}
+		if *greatestN > 0 {
+			reverseVersions(filtered_versions)
+			if uint(len(filtered_versions)) > *greatestN {
+				filtered_versions = filtered_versions[:*greatestN]
+			}
+
+			printVersions(filtered_versions, *greatestDelimiter)
+			break
+		}
+
+		selected := &filtered_versions[len(filtered_versions)-1]
+		if *jsonOutput {
+			considered := []string{}
 			for _, v := range filtered_versions {
-				if v.Metadata() == "" {
-					filtered_build = append(filtered_build, v)
-				}
+				considered = append(considered, v.String())
 			}
-			filtered_versions = filtered_build
+
+			// version and count are the fields of the other selecting
+			// commands and kept for them.
+			printJSON(struct {
+				Version    string           `json:"version"`
+				Count      int              `json:"count"`
+				Selected   string           `json:"selected"`
+				Considered []string         `json:"considered"`
+				Filtered   []droppedVersion `json:"filtered"`
+			}{selected.String(), len(filtered_versions), selected.String(), considered, droppedVersions(rawVersions, *filter_pre_release, *filter_build, *greatestSatisfies)})
+			break
 		}
 
-		sort.Slice(filtered_versions, func(i, j int) bool {
-			return filtered_versions[i].LessThan(&filtered_versions[j])
-		})
+		printSelectedVersion(selected, len(filtered_versions))
 
-		fmt.Println(filtered_versions[len(filtered_versions)-1].String())
-	}
-}
+	case least.FullCommand():
+		filtered_versions := filterSatisfying(filterAndSortVersions(mustParseVersions(readVersionSources(*leastVersions, *leastFile, *leastGitTags), *leastIgnore), *leastFilterPreRelease, *leastFilterBuild), *leastSatisfies)
 
-func mustParseVersion(s, ctx string) *semver.Version {
-	v, err := semver.NewVersion(s)
+		if len(filtered_versions) == 0 {
+			failf(emptyExitCode(), "no versions remain after filtering")
+		}
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to parse <%s> version; %v: '%s'\n", ctx, err, s)
-		os.Exit(-1)
-	}
+		printSelectedVersion(&filtered_versions[0], len(filtered_versions))
 
-	return v
-}
+	case sortCmd.FullCommand():
+		sorted := filterAndSortVersions(mustParseVersions(readVersionSources(*sortVersionList, *sortFile, *sortGitTags), *sortIgnore), *sortFilterPreRelease, *sortFilterBuild)
 
-func mustParseConstraints(s string) *semver.Constraints {
-	c, err := semver.NewConstraint(s)
+		if *sortUnique {
+			sorted = uniqueVersions(sorted)
+		}
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to parse constraints; %v\n", err)
-		os.Exit(-1)
-	}
+		if *sortReverse {
+			reverseVersions(sorted)
+		}
 
-	return c
+		if *sortStableFirst {
+			stableFirst(sorted)
+		}
+
+		if *sortHead > 0 && *sortTail > 0 {
+			fatalf("--head and --tail are mutually exclusive")
+		}
+		if *sortHead > 0 && uint(len(sorted)) > *sortHead {
+			sorted = sorted[:*sortHead]
+		}
+		if *sortTail > 0 && uint(len(sorted)) > *sortTail {
+			sorted = sorted[uint(len(sorted))-*sortTail:]
+		}
+
+		printVersions(sorted, *sortDelimiter)
+
+	case filter.FullCommand():
+		c := mustParseConstraints(*filterConstraints)
+
+		matching := []semver.Version{}
+		for _, v := range mustParseVersions(readVersionSources(*filterVersions, *filterFile, *filterGitTags), *filterIgnore) {
+			if c.Check(&v) != *filterInvert {
+				matching = append(matching, v)
+			}
+		}
+
+		if *filterCount {
+			printCount(len(matching))
+			break
+		}
+
+		if *filterSort {
+			sortVersions(matching)
+		}
+
+		printVersions(matching, *filterDelimiter)
+
+	case validate.FullCommand():
+		codes := mustParseExitCodeMap(*validateExitCodeMap, "valid", "invalid")
+		v, err := semver.NewVersion(versionArg(*validateVersion))
+
+		if err != nil {
+			if *jsonOutput {
+				printJSON(struct {
+					Valid bool   `json:"valid"`
+					Error string `json:"error"`
+				}{false, err.Error()})
+			} else if *verbose {
+				printLine(err)
+			}
+
+			exitResult(codes, "invalid")
+		}
+
+		if *jsonOutput {
+			printJSON(struct {
+				Valid   bool   `json:"valid"`
+				Version string `json:"version"`
+			}{true, v.String()})
+		} else if *verbose {
+			printLine(*outputPrefix + v.String())
+		}
+		exitResult(codes, "valid")
+
+	case validateConstraint.FullCommand():
+		_, err := semver.NewConstraint(*validateConstraintConstraints)
+
+		if *jsonOutput {
+			message := ""
+			if err != nil {
+				message = err.Error()
+			}
+
+			printJSON(struct {
+				Valid bool   `json:"valid"`
+				Error string `json:"error"`
+			}{err == nil, message})
+		} else if *verbose && err != nil {
+			printLine(err)
+		}
+
+		if err != nil {
+			exitFalse()
+		}
+		os.Exit(0)
+
+	case coerce.FullCommand():
+		v := mustParseVersion(*coerceVersion, "VERSION")
+		input := versionArg(*coerceVersion)
+
+		coerced := v.String()
+		if *coerceKeepV && strings.HasPrefix(input, "v") {
+			coerced = "v" + coerced
+		}
+
+		if *coerceStrict {
+			if _, err := semver.StrictNewVersion(input); err != nil {
+				fatalf("version requires coercion; %v: '%s'", err, *coerceVersion)
+			}
+		}
+
+		if *jsonOutput {
+			printJSON(struct {
+				Version  string `json:"version"`
+				Original string `json:"original"`
+			}{coerced, *coerceVersion})
+			break
+		}
+
+		if *verbose {
+			printLine(*coerceVersion)
+		}
+		printLine(*outputPrefix + *coercePrefix + coerced)
+
+	case diff.FullCommand():
+		a := mustParseVersion(*diffA, "A")
+		b := mustParseVersion(*diffB, "B")
+
+		change := "NONE"
+		changes := []componentChange{}
+		for _, c := range versionComponents(a, b) {
+			if c.Old == c.New {
+				continue
+			}
+
+			changes = append(changes, c)
+			if change == "NONE" {
+				change = c.change
+			}
+		}
+
+		if *jsonOutput {
+			printJSON(struct {
+				Changes []componentChange `json:"changes"`
+				Change  string            `json:"change"`
+			}{changes, change})
+		} else if *diffBrief {
+			if len(changes) == 0 {
+				printLine("equal")
+			} else {
+				printLine(changes[0].Component)
+			}
+		} else {
+			for _, c := range changes {
+				printLinef("%s: %s -> %s", c.Component, orNone(c.Old), orNone(c.New))
+			}
+			printLine(change)
+		}
+
+		if *diffExitCode {
+			switch change {
+			case "NONE":
+				os.Exit(0)
+			case "MAJOR":
+				os.Exit(3)
+			case "MINOR":
+				os.Exit(2)
+			default:
+				os.Exit(1)
+			}
+		}
+
+	case compare.FullCommand():
+		args := append([]string{*compareA, *compareB}, *compareMore...)
+		if *compareFile != "" {
+			args = append([]string{mustVersionArg("", "", *compareFile)}, args...)
+		}
+		// Drop the positionals which were not given.
+		for len(args) > 0 && args[len(args)-1] == "" {
+			args = args[:len(args)-1]
+		}
+		if len(args) < 2 {
+			fatalf("required argument '%s' not provided", []string{"A", "B"}[len(args)])
+		}
+
+		if len(args) > 2 || *compareDesc {
+			op := "<"
+			if *compareDesc {
+				op = ">"
+			}
+
+			exitOrdered(orderViolation(mustParseVersions(args, false), op))
+			break
+		}
+
+		a := mustParseVersion(args[0], "A")
+		b := mustParseVersion(args[1], "B")
+
+		result := a.Compare(b)
+		symbol := []string{"<", "=", ">"}[result+1]
+
+		if *jsonOutput {
+			printJSON(struct {
+				Comparison string `json:"comparison"`
+				Result     int    `json:"result"`
+			}{symbol, result})
+		} else if *compareNumeric {
+			printLine(result)
+		} else {
+			printLine(symbol)
+		}
+
+	case checkSorted.FullCommand():
+		op := "<="
+		if *checkSortedStrict {
+			op = "<"
+		}
+
+		exitOrdered(orderViolation(mustParseVersions(readVersionArgs(*checkSortedVersions, *checkSortedFile), *checkSortedIgnore), op))
+
+	case between.FullCommand():
+		codes := mustParseExitCodeMap(*betweenExitCodeMap, "between", "not-between")
+		v := mustParseVersion(*betweenVersion, "VERSION")
+		lower := mustParseVersion(*betweenMin, "MIN")
+		upper := mustParseVersion(*betweenMax, "MAX")
+
+		minOp, maxOp := ">=", "<="
+		if *betweenExclusiveMin {
+			minOp = ">"
+		}
+		if *betweenExclusiveMax {
+			maxOp = "<"
+		}
+
+		var violation string
+		if c := v.Compare(lower); c < 0 || (c == 0 && *betweenExclusiveMin) {
+			violation = fmt.Sprintf("%s is not %s lower bound %s", v, minOp, lower)
+		} else if c := v.Compare(upper); c > 0 || (c == 0 && *betweenExclusiveMax) {
+			violation = fmt.Sprintf("%s is not %s upper bound %s", v, maxOp, upper)
+		}
+
+		if *jsonOutput {
+			printJSON(struct {
+				Between bool   `json:"between"`
+				Reason  string `json:"reason,omitempty"`
+			}{violation == "", violation})
+		}
+
+		if violation != "" {
+			if *verbose && !*jsonOutput {
+				printLine(violation)
+			}
+
+			exitResult(codes, "not-between")
+		}
+
+		exitResult(codes, "between")
+
+	case strip.FullCommand():
+		v := mustParseVersion(*stripVersion, "VERSION")
+		var v1 semver.Version
+		switch *stripComponent {
+		case "prerelease":
+			v1, _ = v.SetPrerelease("")
+		case "metadata":
+			v1, _ = v.SetMetadata("")
+		case "all":
+			v1 = *semver.New(v.Major(), v.Minor(), v.Patch(), "", "")
+		default:
+			fatalf("unknown component name: '%s'", *stripComponent)
+		}
+		printVersion(&v1, *stripPrefix)
+
+	case format.FullCommand():
+		if *formatTemplate != "" {
+			// With --template the only argument is the version.
+			if *formatVersion != "" {
+				fatalf("pass the template either as argument or with --template, not both")
+			}
+			*formatVersion = *formatTemplateArg
+			if *formatVersion == "" {
+				fatalf("required argument 'VERSION' not provided")
+			}
+		} else {
+			if *formatTemplateArg == "" {
+				fatalf("required argument 'TEMPLATE' not provided")
+			}
+			if *formatVersion == "" {
+				fatalf("required argument 'VERSION' not provided")
+			}
+			*formatTemplate = *formatTemplateArg
+		}
+
+		v := mustParseVersion(*formatVersion, "VERSION")
+		data := formatData{v.Major(), v.Minor(), v.Patch(), v.Prerelease(), v.Metadata(), v.Original()}
+
+		t, err := template.New("format").Parse(*formatTemplate)
+		if err != nil {
+			fatalf("Failed to parse template; %v", err)
+		}
+
+		if *jsonOutput {
+			printJSON(data)
+			break
+		}
+
+		var out strings.Builder
+		if err := t.Execute(&out, data); err != nil {
+			fatalf("Failed to render template; %v", err)
+		}
+		printLine(out.String())
+
+	case parse.FullCommand():
+		v := mustParseVersion(*parseVersion, "VERSION")
+
+		if *jsonOutput || *parseFormat == "json" {
+			printJSON(newVersionJSON(v))
+			break
+		}
+
+		if *parseFormat == "env" {
+			printEnv(v, "SEMVER_")
+			break
+		}
+
+		for _, name := range componentNames {
+			value, _ := componentValue(v, name)
+			printLinef("%s=%s", name, value)
+		}
+
+	case export.FullCommand():
+		v := mustParseVersion(*exportVersion, "VERSION")
+
+		if *jsonOutput {
+			printJSON(newVersionJSON(v))
+			break
+		}
+
+		printEnv(v, *exportPrefix)
+
+	case unique.FullCommand():
+		unique := uniqueVersions(mustParseVersions(readVersionArgs(*uniqueVersionList, *uniqueFile), *uniqueIgnore))
+
+		if *uniqueCount {
+			printCount(len(unique))
+			break
+		}
+
+		if *uniqueSort {
+			sortVersions(unique)
+		}
+
+		printVersions(unique, *uniqueDelimiter)
+
+	case next.FullCommand():
+		v := mustParseVersion(*nextVersion, "VERSION")
+
+		if *nextPreID != "" {
+			if _, err := v.SetPrerelease(*nextPreID); err != nil {
+				fatalf("invalid prerelease; %v", err)
+			}
+		}
+
+		var v1 *semver.Version
+		switch *nextComponent {
+		case "major":
+			v1 = semver.New(v.Major()+1, 0, 0, "", "")
+		case "minor":
+			v1 = semver.New(v.Major(), v.Minor()+1, 0, "", "")
+		case "patch":
+			v1 = semver.New(v.Major(), v.Minor(), v.Patch()+1, "", "")
+		case "pre":
+			v1 = nextPrerelease(v, *nextPreID)
+		default:
+			fatalf("unknown component name: '%s'", *nextComponent)
+		}
+
+		if *nextPreID != "" && *nextComponent != "pre" {
+			v2, _ := v1.SetPrerelease(*nextPreID)
+			v1 = &v2
+		}
+		printVersion(v1, *nextPrefix)
+
+	case clamp.FullCommand():
+		v := mustParseVersion(*clampVersion, "VERSION")
+		lower := mustParseVersion(*clampMin, "MIN")
+		upper := mustParseVersion(*clampMax, "MAX")
+
+		if lower.GreaterThan(upper) {
+			fatalf("MIN must not be greater than MAX: '%s' > '%s'", *clampMin, *clampMax)
+		}
+
+		clamped := v
+		if v.LessThan(lower) {
+			clamped = lower
+		} else if v.GreaterThan(upper) {
+			clamped = upper
+		}
+
+		if *jsonOutput {
+			printJSON(struct {
+				Version string `json:"version"`
+				Clamped bool   `json:"clamped"`
+			}{clamped.String(), clamped != v})
+		} else {
+			printLine(*outputPrefix + clamped.String())
+		}
+
+		if *clampExitCode && clamped != v {
+			os.Exit(1)
+		}
+
+	case count.FullCommand():
+		// Before --satisfies the constraint was the first argument. Point
+		// there rather than skip it with --ignore-invalid.
+		if args := *countVersions; len(args) > 0 && args[0] != "" {
+			if _, err := semver.NewVersion(versionArg(args[0])); err != nil {
+				if _, err := semver.NewConstraint(args[0]); err == nil {
+					fatalf("'%s' is a constraint, not a version; pass constraints with --satisfies", args[0])
+				}
+			}
+		}
+
+		n := len(filterSatisfying(filterAndSortVersions(mustParseVersions(readVersionArgs(*countVersions, *countFile), *countIgnore), *countFilterPreRelease, *countFilterBuild), *countSatisfies))
+
+		if n == 0 && *countExitIfEmpty {
+			exitFalse()
+		}
+		printCount(n)
+
+	case bump.FullCommand():
+		v := mustParseVersion(*bumpVersion, "VERSION")
+
+		level := *bumpLevel
+		if level == "auto" {
+			if !bumpCommitsFileSet {
+				fatalf("--level=auto requires --commits-file")
+			}
+
+			level = mustReadCommitLevel(*bumpCommitsFile)
+			if *verbose {
+				fmt.Fprintf(os.Stderr, "Level from commits: %s\n", orNone(level))
+			}
+		}
+
+		v1 := *v
+		switch level {
+		case "major":
+			v1 = v.IncMajor()
+		case "minor":
+			v1 = v.IncMinor()
+		case "patch":
+			v1 = v.IncPatch()
+		}
+		printVersion(&v1, *bumpPrefix)
+
+	case maxSatisfying.FullCommand():
+		c := mustParseConstraints(*maxSatisfyingConstraints)
+
+		matching := []semver.Version{}
+		for _, v := range mustParseVersions(readVersionArgs(*maxSatisfyingVersions, ""), false) {
+			if ok, _ := c.Validate(&v); ok {
+				matching = append(matching, v)
+			}
+		}
+
+		if len(matching) == 0 {
+			if *verbose {
+				fmt.Fprintf(os.Stderr, "no version satisfies '%s'\n", *maxSatisfyingConstraints)
+			}
+			exitFalse()
+		}
+
+		sortVersions(matching)
+		printSelectedVersion(&matching[len(matching)-1], len(matching))
+
+	case latestStable.FullCommand():
+		stable := filterAndSortVersions(mustParseVersions(readVersionArgs(*latestStableVersions, *latestStableFile), *latestStableIgnore), true, true)
+
+		if len(stable) == 0 {
+			if *verbose {
+				fmt.Fprintln(os.Stderr, "no stable version found")
+			}
+			exitFalse()
+		}
+
+		printSelectedVersion(&stable[len(stable)-1], len(stable))
+
+	case update.FullCommand():
+		raw := mustVersionArg("", "", *updateFile)
+		v1 := mustIncrement(mustParseVersion(raw, "VERSION"), *updateComponent)
+
+		prefix := versionFilePrefix(*updatePrefix, *updateFile, raw)
+		writeVersionFile(*updateFile, prefix+v1.String())
+		printVersion(&v1, prefix)
+
+	case matchesAny.FullCommand():
+		v := mustParseVersion(*matchesAnyVersion, "VERSION")
+
+		f, err := os.Open(*matchesAnyConstraintsFile)
+		if err != nil {
+			fatalf("Failed to read constraints; %v", err)
+		}
+		defer f.Close()
+
+		// Parse the whole file first so that a malformed line is reported
+		// even if an earlier one matches.
+		constraints := []*semver.Constraints{}
+		lines := []string{}
+		scanner := bufio.NewScanner(f)
+		for n := 1; scanner.Scan(); n++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			c, err := semver.NewConstraint(line)
+			if err != nil {
+				fatalf("Failed to parse constraints in %s line %d; %v", *matchesAnyConstraintsFile, n, err)
+			}
+			constraints = append(constraints, c)
+			lines = append(lines, line)
+		}
+
+		if err := scanner.Err(); err != nil {
+			fatalf("Failed to read constraints; %v", err)
+		}
+
+		match := ""
+		for i, c := range constraints {
+			if c.Check(v) {
+				match = lines[i]
+				break
+			}
+		}
+
+		if *jsonOutput {
+			printJSON(struct {
+				Matches    bool   `json:"matches"`
+				Constraint string `json:"constraint,omitempty"`
+			}{match != "", match})
+		} else if *verbose && match != "" {
+			printLine(match)
+		}
+
+		if match == "" {
+			exitFalse()
+		}
+
+	case constraintExpand.FullCommand():
+		mustParseConstraints(*constraintExpandConstraints)
+		expanded := expandConstraints(*constraintExpandConstraints)
+
+		if *jsonOutput {
+			printJSON(struct {
+				Constraints string `json:"constraints"`
+				Expanded    string `json:"expanded"`
+			}{*constraintExpandConstraints, expanded})
+			break
+		}
+
+		printLine(expanded)
+
+	case negate.FullCommand():
+		mustParseConstraints(*negateConstraint)
+
+		negated, err := negateConstraints(*negateConstraint)
+		if err != nil {
+			fatalf("Failed to negate constraints; %v", err)
+		}
+
+		if *jsonOutput {
+			printJSON(struct {
+				Constraints string `json:"constraints"`
+				Negated     string `json:"negated"`
+			}{*negateConstraint, negated})
+			break
+		}
+
+		printLine(negated)
+
+	case difference.FullCommand():
+		a, b := mustSplitVersionLists(*differenceLists, *differenceSeparator)
+
+		result := []semver.Version{}
+		for _, v := range a {
+			if !containsVersion(b, &v) {
+				result = append(result, v)
+			}
+		}
+
+		printVersions(setResult(result, *differenceSort), *differenceDelimiter)
+
+	case intersection.FullCommand():
+		a, b := mustSplitVersionLists(*intersectionLists, *intersectionSeparator)
+
+		result := []semver.Version{}
+		for _, v := range a {
+			if containsVersion(b, &v) {
+				result = append(result, v)
+			}
+		}
+
+		printVersions(setResult(result, *intersectionSort), *intersectionDelimiter)
+
+	case union.FullCommand():
+		a, b := mustSplitVersionLists(*unionLists, *unionSeparator)
+
+		printVersions(setResult(append(a, b...), *unionSort), *unionDelimiter)
+
+	case sequence.FullCommand():
+		result := mustVersionSequence(*sequenceA, *sequenceB, *sequenceComponent, *sequenceExclusive || !*sequenceInclusive)
+		printVersions(result, *sequenceDelimiter)
+
+	case dedup.FullCommand():
+		unique := uniqueVersions(mustParseVersions(readVersionArgs(*dedupVersions, *dedupFile), *dedupIgnore))
+		sortVersions(unique)
+
+		list := []string{}
+		for _, v := range unique {
+			if *dedupKeepOriginal {
+				list = append(list, v.Original())
+			} else {
+				list = append(list, v.String())
+			}
+		}
+		printVersionStrings(list, *dedupDelimiter)
+
+	case rangeCmd.FullCommand():
+		printVersions(mustVersionSequence(*rangeA, *rangeB, *rangeBy, false), *rangeDelimiter)
+
+	case completion.FullCommand():
+		ctx, err := app.ParseContext(nil)
+		if err == nil {
+			err = app.UsageWriter(os.Stdout).UsageForContextWithTemplate(ctx, 2, completionTemplates[*completionShell])
+		}
+
+		if err != nil {
+			fatalf("Failed to generate completion script; %v", err)
+		}
+	}
+}
+
+// emptyExitCode is the exit code for greatest and least without any versions
+// left: --empty-exit-code if given, else the --error-exit-code.
+func emptyExitCode() int {
+	if emptyExitSet {
+		return *emptyExit
+	}
+	return *errorExit
+}
+
+// exitFalse exits with the code for a test which does not hold.
+func exitFalse() {
+	os.Exit(*falseExit)
+}
+
+// mustParseExitCodeMap parses an --exit-code-map like satisfied=0,unsatisfied=3
+// for a test with the given results. Results which are not mentioned keep
+// their default: 0 if the test holds and --false-exit-code if not.
+func mustParseExitCodeMap(s string, holds, fails string) map[string]int {
+	codes := map[string]int{holds: 0, fails: *falseExit}
+	if s == "" {
+		return codes
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if _, ok := codes[parts[0]]; !ok || len(parts) != 2 {
+			fatalf("invalid exit code mapping '%s'; expected %s=N or %s=N", pair, holds, fails)
+		}
+
+		code, err := strconv.Atoi(parts[1])
+		if err != nil {
+			fatalf("invalid exit code mapping '%s'; %v", pair, err)
+		}
+		codes[parts[0]] = code
+	}
+
+	return codes
+}
+
+// exitResult exits with the code for the result of a test.
+func exitResult(codes map[string]int, result string) {
+	os.Exit(codes[result])
+}
+
+// mustVersionArg returns the version argument, the version from the
+// environment variable env or the version stored in file, whichever is given.
+// Exactly one of them is required.
+func mustVersionArg(arg, env, file string) string {
+	given := 0
+	for _, source := range []string{arg, env, file} {
+		if source != "" {
+			given++
+		}
+	}
+
+	switch {
+	case given == 0:
+		fatalf("required argument 'VERSION' not provided")
+	case given > 1:
+		fatalf("pass the version only once: as argument, with --from-env or with --file")
+	case env != "":
+		v := os.Getenv(env)
+		if v == "" {
+			fatalf("environment variable %s is unset or empty", env)
+		}
+		return v
+	case file != "":
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			fatalf("Failed to read version; %v", err)
+		}
+		v := strings.TrimSpace(string(content))
+		if v == "" {
+			fatalf("version file %s is empty", file)
+		}
+		return v
+	}
+
+	return arg
+}
+
+// versionFilePrefix returns the prefix to print a version with. Without one,
+// a version read from a file keeps its leading v, so that writing it back
+// keeps the format of the file.
+func versionFilePrefix(prefix, file, raw string) string {
+	if prefix == "" && file != "" && strings.HasPrefix(raw, "v") {
+		return "v"
+	}
+
+	return prefix
+}
+
+// writeVersionFile replaces the content of file with the version s. The
+// version is written to a temporary file which is then renamed, so the file
+// is never left half written.
+func writeVersionFile(file, s string) {
+	if file == "" {
+		fatalf("--write requires --file")
+	}
+
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(file); err == nil {
+		mode = fi.Mode()
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file)+".*")
+	if err == nil {
+		_, err = tmp.WriteString(s + "\n")
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(tmp.Name(), mode)
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), file)
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}
+
+	if err != nil {
+		fatalf("Failed to write version; %v", err)
+	}
+}
+
+// countArg returns a kingpin action which counts the given arguments.
+func countArg(n *int) kingpin.Action {
+	return func(*kingpin.ParseContext) error {
+		*n++
+		return nil
+	}
+}
+
+// fatalf reports an error on stderr, as JSON with --json-errors, and exits
+// with the error exit code.
+func fatalf(format string, a ...interface{}) {
+	failf(*errorExit, format, a...)
+}
+
+// failf is fatalf exiting with the given code.
+func failf(code int, format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+
+	if *jsonErrors {
+		printJSONError(msg, code)
+	} else {
+		fmt.Fprintln(os.Stderr, msg)
+	}
+
+	os.Exit(code)
+}
+
+// printJSONError prints an error and the code the process exits with as JSON
+// to stderr.
+func printJSONError(msg string, code int) {
+	enc := json.NewEncoder(os.Stderr)
+	enc.SetEscapeHTML(false)
+	enc.Encode(struct {
+		Error string `json:"error"`
+		Code  int    `json:"code"`
+	}{msg, code})
+}
+
+// orderViolation returns the first pair of neighbors among the versions for
+// which the comparison op, one of <, <= or >, does not hold, as a message.
+// It is empty if the versions are in order.
+func orderViolation(vs []semver.Version, op string) string {
+	for i := 1; i < len(vs); i++ {
+		c := vs[i-1].Compare(&vs[i])
+		if (op == "<" && c >= 0) || (op == "<=" && c > 0) || (op == ">" && c <= 0) {
+			return fmt.Sprintf("%s is not %s %s", vs[i-1].Original(), op, vs[i].Original())
+		}
+	}
+
+	return ""
+}
+
+// exitOrdered reports the result of an order test and exits false if there
+// is a violation.
+func exitOrdered(violation string) {
+	if *jsonOutput {
+		printJSON(struct {
+			Ordered bool   `json:"ordered"`
+			Reason  string `json:"reason,omitempty"`
+		}{violation == "", violation})
+	}
+
+	if violation != "" {
+		if *verbose && !*jsonOutput {
+			printLine(violation)
+		}
+		exitFalse()
+	}
+}
+
+// versionArg applies the global input options to a version argument.
+func versionArg(s string) string {
+	s = strings.TrimPrefix(s, *inputPrefix)
+	if *stripV {
+		s = strings.TrimPrefix(s, "v")
+	}
+
+	return s
+}
+
+func mustParseVersion(s, ctx string) *semver.Version {
+	v, err := semver.NewVersion(versionArg(s))
+
+	if err != nil {
+		fatalf("Failed to parse <%s> version; %v: '%s'", ctx, err, s)
+	}
+
+	return v
+}
+
+// mustParseVersions parses all versions. With ignoreInvalid, versions which
+// fail to parse are skipped instead.
+func mustParseVersions(raw []string, ignoreInvalid bool) []semver.Version {
+	vs := []semver.Version{}
+	for _, s := range raw {
+		if !ignoreInvalid {
+			vs = append(vs, *mustParseVersion(s, "VERSION"))
+			continue
+		}
+
+		v, err := semver.NewVersion(versionArg(s))
+		if err != nil {
+			if *verbose {
+				fmt.Fprintf(os.Stderr, "Skipping invalid version; %v: '%s'\n", err, s)
+			}
+			continue
+		}
+		vs = append(vs, *v)
+	}
+
+	return vs
+}
+
+// readVersionSources is readVersionArgs preceded by the versions among the
+// git tags with fromGitTags. Then stdin is only read for '-'.
+func readVersionSources(args []string, file string, fromGitTags bool) []string {
+	if !fromGitTags {
+		return readVersionArgs(args, file)
+	}
+
+	raw := gitVersionTags()
+	if len(args) > 0 || file != "" {
+		raw = append(raw, readVersionArgs(args, file)...)
+	}
+
+	return raw
+}
+
+// gitVersionTags returns the tags of the git repository in the working
+// directory which parse as versions.
+func gitVersionTags() []string {
+	out, err := exec.Command("git", "tag", "--list").Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			err = fmt.Errorf("%v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		fatalf("Failed to list git tags; %v", err)
+	}
+
+	tags := []string{}
+	for _, tag := range strings.Split(string(out), "\n") {
+		if _, err := semver.NewVersion(versionArg(tag)); err == nil {
+			tags = append(tags, tag)
+		} else if *verbose && tag != "" {
+			fmt.Fprintf(os.Stderr, "Skipping tag which is not a version: '%s'\n", tag)
+		}
+	}
+
+	return tags
+}
+
+// readVersionArgs returns the versions read from file, if any, followed by
+// the version arguments with every '-' replaced by the lines read from stdin.
+// Without a file or any arguments stdin is read as well, unless it is a
+// terminal.
+func readVersionArgs(args []string, file string) []string {
+	raw := []string{}
+
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			fatalf("Failed to read versions; %v", err)
+		}
+		defer f.Close()
+
+		raw = readVersionLines(f, file, false)
+	} else if len(args) == 0 {
+		if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+			if !*jsonErrors {
+				app.Usage(os.Args[1:])
+			}
+			fatalf("no versions given; pass them as arguments or on stdin")
+		}
+
+		args = []string{"-"}
+	}
+
+	for _, a := range args {
+		// kingpin hands over a lone '-' as an empty argument.
+		if a != "-" && a != "" {
+			raw = append(raw, a)
+			continue
+		}
+
+		raw = append(raw, readVersionLines(os.Stdin, "stdin", *nullDelim)...)
+	}
+
+	return raw
+}
+
+// readVersionLines reads one version per line. With nul the input is split
+// at NUL bytes instead if it contains any. Surrounding whitespace is trimmed,
+// blank lines and lines starting with '#' are skipped.
+func readVersionLines(r io.Reader, name string, nul bool) []string {
+	lines := []string{}
+
+	scanner := bufio.NewScanner(r)
+	if nul {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			fatalf("Failed to read versions from %s; %v", name, err)
+		}
+
+		scanner = bufio.NewScanner(bytes.NewReader(data))
+		if bytes.IndexByte(data, 0) >= 0 {
+			scanner.Split(scanNUL)
+		}
+	}
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		fatalf("Failed to read versions from %s; %v", name, err)
+	}
+
+	return lines
+}
+
+// scanNUL is a bufio.SplitFunc for records terminated by NUL bytes.
+func scanNUL(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
+
+func mustParseNumber(s, ctx string) uint64 {
+	n, err := strconv.ParseUint(s, 10, 64)
+
+	if err != nil {
+		fatalf("invalid %s, must be a non-negative integer: '%s'", ctx, s)
+	}
+
+	return n
+}
+
+func mustParseConstraints(s string) *semver.Constraints {
+	c, err := semver.NewConstraint(s)
+
+	if err != nil {
+		fatalf("Failed to parse constraints; %v", err)
+	}
+
+	return c
+}
+
+// satisfiesConstraintArgs collects the constraints of the satisfies command,
+// the positional one first.
+func satisfiesConstraintArgs() []string {
+	raw := append([]string{}, *satisfiesConstraint...)
+	if *satisfiesConstraints != "" {
+		raw = append([]string{*satisfiesConstraints}, raw...)
+	}
+
+	if *satisfiesConstraintEnv != "" {
+		c := os.Getenv(*satisfiesConstraintEnv)
+		if c == "" {
+			fatalf("environment variable %s is unset or empty; set it to the constraints to test against", *satisfiesConstraintEnv)
+		}
+		raw = append(raw, c)
+	}
+
+	if len(raw) == 0 {
+		fatalf("no constraints given; pass CONSTRAINTS, --constraint or --constraint-env")
+	}
+
+	return raw
+}
+
+// checkConstraints tests v against each constraint on its own, so one
+// containing || keeps its meaning. With any a single satisfied constraint is
+// enough, otherwise every one of them has to hold. It returns the messages of
+// the failed constraints and the satisfied ones.
+func checkConstraints(v *semver.Version, raw []string, any bool) (bool, []error, []string) {
+	does := !any
+	var msgs []error
+	var satisfied []string
+	for _, r := range raw {
+		c := mustParseConstraints(r)
+		ok, errs := c.Validate(v)
+		if *satisfiesIncludePre && v.Prerelease() != "" {
+			ok, errs = validateIncludingPrerelease(v, r)
+		}
+
+		if ok {
+			satisfied = append(satisfied, r)
+		} else {
+			msgs = append(msgs, errs...)
+		}
+
+		if any {
+			does = does || ok
+		} else {
+			does = does && ok
+		}
+	}
+
+	return does, msgs, satisfied
+}
+
+// filterSatisfying returns the versions which satisfy every one of the
+// constraints, keeping their order.
+func filterSatisfying(vs []semver.Version, raw []string) []semver.Version {
+	constraints := []*semver.Constraints{}
+	for _, r := range raw {
+		constraints = append(constraints, mustParseConstraints(r))
+	}
+
+	matching := []semver.Version{}
+	for _, v := range vs {
+		ok := true
+		for _, c := range constraints {
+			ok = ok && c.Check(&v)
+		}
+
+		if ok {
+			matching = append(matching, v)
+		}
+	}
+
+	return matching
+}
+
+// mustVersionSequence returns every version from a to b, incrementing the
+// component, optionally without a and b themselves.
+func mustVersionSequence(rawA, rawB, component string, exclusive bool) []semver.Version {
+	a := mustParseVersion(rawA, "A")
+	b := mustParseVersion(rawB, "B")
+
+	if b.LessThan(a) {
+		fatalf("B must not be less than A: '%s' < '%s'", rawB, rawA)
+	}
+
+	// Incrementing a component never changes the ones above it, so B
+	// has to share them with A to be reached.
+	if (a.Major() != b.Major() && component != "major") || (a.Minor() != b.Minor() && component == "patch") {
+		fatalf("'%s' cannot be reached from '%s' by incrementing %s", rawB, rawA, component)
+	}
+
+	result := []semver.Version{}
+	for v := *a; !v.GreaterThan(b); {
+		if !exclusive || !v.Equal(a) && !v.Equal(b) {
+			result = append(result, v)
+		}
+
+		switch component {
+		case "major":
+			v = v.IncMajor()
+		case "minor":
+			v = v.IncMinor()
+		case "patch":
+			v = v.IncPatch()
+		}
+	}
+
+	return result
+}
+
+// mustSplitVersionLists splits the arguments of a set operation at the
+// separator and parses both lists.
+func mustSplitVersionLists(args []string, separator string) ([]semver.Version, []semver.Version) {
+	i := -1
+	if separator == "--" {
+		// kingpin swallows the first '--' and passes everything after it
+		// on as arguments, so find it on the raw command line instead.
+		for j, a := range os.Args[1:] {
+			if a == "--" {
+				i = len(args) - (len(os.Args) - 2 - j)
+				break
+			}
+		}
+	} else {
+		for j, a := range args {
+			if a == separator {
+				i = j
+				break
+			}
+		}
+	}
+
+	if i < 0 {
+		fatalf("missing separator '%s' between the two lists", separator)
+	}
+
+	b := args[i:]
+	if separator != "--" {
+		b = b[1:]
+	}
+
+	return mustParseVersions(args[:i], false), mustParseVersions(b, false)
+}
+
+// containsVersion reports whether vs contains a version equal to v.
+func containsVersion(vs []semver.Version, v *semver.Version) bool {
+	for _, u := range vs {
+		if u.Equal(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setResult drops duplicates from the result of a set operation and sorts it
+// if requested.
+func setResult(vs []semver.Version, sorted bool) []semver.Version {
+	vs = uniqueVersions(vs)
+	if sorted {
+		sortVersions(vs)
+	}
+
+	return vs
+}
+
+// droppedVersion is a version which was filtered out and why.
+type droppedVersion struct {
+	Version string `json:"version"`
+	Reason  string `json:"reason"`
+}
+
+// droppedVersions returns the versions which mustParseVersions,
+// filterAndSortVersions and filterSatisfying drop with the same options, in
+// input order. The reason is one of invalid, prerelease, build or
+// constraint.
+func droppedVersions(raw []string, filterPreRelease, filterBuild bool, satisfies []string) []droppedVersion {
+	constraints := []*semver.Constraints{}
+	for _, r := range satisfies {
+		constraints = append(constraints, mustParseConstraints(r))
+	}
+
+	dropped := []droppedVersion{}
+	for _, s := range raw {
+		v, err := semver.NewVersion(versionArg(s))
+
+		reason := ""
+		switch {
+		case err != nil:
+			reason = "invalid"
+		case filterPreRelease && v.Prerelease() != "":
+			reason = "prerelease"
+		case filterBuild && v.Metadata() != "":
+			reason = "build"
+		default:
+			for _, c := range constraints {
+				if !c.Check(v) {
+					reason = "constraint"
+					break
+				}
+			}
+		}
+
+		if reason != "" {
+			dropped = append(dropped, droppedVersion{s, reason})
+		}
+	}
+
+	return dropped
+}
+
+// filterAndSortVersions drops the versions with pre-release or build
+// information if requested and returns the rest in ascending order.
+func filterAndSortVersions(all_parsed_versions []semver.Version, filterPreRelease, filterBuild bool) []semver.Version {
+	filtered_versions := all_parsed_versions
+
+	if filterPreRelease {
+		filtered_pre_release := []semver.Version{}
+		for _, v := range all_parsed_versions {
+			if v.Prerelease() == "" {
+				filtered_pre_release = append(filtered_pre_release, v)
+			}
+		}
+		filtered_versions = filtered_pre_release
+	}
+
+	if filterBuild {
+		filtered_build := []semver.Version{}
+		for _, v := range filtered_versions {
+			if v.Metadata() == "" {
+				filtered_build = append(filtered_build, v)
+			}
+		}
+		filtered_versions = filtered_build
+	}
+
+	sortVersions(filtered_versions)
+
+	return filtered_versions
+}
+
+// reverseVersions reverses the order of the versions in place.
+func reverseVersions(vs []semver.Version) {
+	for i, j := 0, len(vs)-1; i < j; i, j = i+1, j-1 {
+		vs[i], vs[j] = vs[j], vs[i]
+	}
+}
+
+// stableFirst moves releases before the pre-releases with the same major,
+// minor and patch in place. The versions must be sorted, so that those are
+// next to each other. The order is otherwise kept.
+func stableFirst(vs []semver.Version) {
+	for i := 0; i < len(vs); {
+		j := i + 1
+		for j < len(vs) && vs[j].Major() == vs[i].Major() && vs[j].Minor() == vs[i].Minor() && vs[j].Patch() == vs[i].Patch() {
+			j++
+		}
+
+		run := vs[i:j]
+		sort.SliceStable(run, func(a, b int) bool {
+			return run[a].Prerelease() == "" && run[b].Prerelease() != ""
+		})
+		i = j
+	}
+}
+
+// sortVersions sorts the versions in place in ascending order.
+func sortVersions(vs []semver.Version) {
+	sort.Slice(vs, func(i, j int) bool {
+		return vs[i].LessThan(&vs[j])
+	})
+}
+
+// uniqueVersions returns the versions without duplicates, keeping the first
+// occurrence of every version which is equal to a previous one.
+func uniqueVersions(vs []semver.Version) []semver.Version {
+	unique := []semver.Version{}
+	for i := range vs {
+		seen := false
+		for j := range unique {
+			if unique[j].Equal(&vs[i]) {
+				seen = true
+				break
+			}
+		}
+
+		if !seen {
+			unique = append(unique, vs[i])
+		}
+	}
+
+	return unique
+}
+
+// formatData is what the template of the format command is rendered with.
+type formatData struct {
+	Major      uint64 `json:"major"`
+	Minor      uint64 `json:"minor"`
+	Patch      uint64 `json:"patch"`
+	Prerelease string `json:"prerelease"`
+	Metadata   string `json:"metadata"`
+	Original   string `json:"original"`
+}
+
+type componentChange struct {
+	Component string `json:"component"`
+	Old       string `json:"old"`
+	New       string `json:"new"`
+	change    string
+}
+
+// componentNames lists the components of a version from the most to the
+// least significant one.
+var componentNames = []string{"major", "minor", "patch", "prerelease", "metadata"}
+
+// componentValue returns the named component of the version, or false if
+// there is no such component.
+func componentValue(v *semver.Version, name string) (string, bool) {
+	switch name {
+	case "major":
+		return strconv.FormatUint(v.Major(), 10), true
+	case "minor":
+		return strconv.FormatUint(v.Minor(), 10), true
+	case "patch":
+		return strconv.FormatUint(v.Patch(), 10), true
+	case "prerelease":
+		return v.Prerelease(), true
+	case "metadata":
+		return v.Metadata(), true
+	case "core":
+		core := fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.Patch())
+		if strings.HasPrefix(v.Original(), "v") {
+			core = "v" + core
+		}
+		return core, true
+	}
+
+	return "", false
+}
+
+// versionComponents lists all components of both versions from the most to
+// the least significant one.
+func versionComponents(a, b *semver.Version) []componentChange {
+	return []componentChange{
+		{"major", strconv.FormatUint(a.Major(), 10), strconv.FormatUint(b.Major(), 10), "MAJOR"},
+		{"minor", strconv.FormatUint(a.Minor(), 10), strconv.FormatUint(b.Minor(), 10), "MINOR"},
+		{"patch", strconv.FormatUint(a.Patch(), 10), strconv.FormatUint(b.Patch(), 10), "PATCH"},
+		{"prerelease", a.Prerelease(), b.Prerelease(), "PRE-RELEASE"},
+		{"metadata", a.Metadata(), b.Metadata(), "METADATA"},
+	}
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+
+	return s
+}
+
+// mustIncrement increments the major, minor, patch or prerelease component
+// of v.
+func mustIncrement(v *semver.Version, component string) semver.Version {
+	switch component {
+	case "major":
+		return v.IncMajor()
+	case "minor":
+		return v.IncMinor()
+	case "patch":
+		return v.IncPatch()
+	case "prerelease":
+		if v.Prerelease() == "" {
+			fatalf("version has no prerelease to increment: '%s'", v.Original())
+		}
+		return *semver.New(v.Major(), v.Minor(), v.Patch(), incPrerelease(v.Prerelease()), "")
+	}
+
+	fatalf("unknown component name: '%s'", component)
+	return *v
+}
+
+// incPrerelease increments the trailing numeric identifier of a prerelease,
+// e.g. rc.1 becomes rc.2. Without one, .1 is appended.
+func incPrerelease(pre string) string {
+	ids := strings.Split(pre, ".")
+	last := ids[len(ids)-1]
+
+	if n, err := strconv.ParseUint(last, 10, 64); err == nil {
+		ids[len(ids)-1] = strconv.FormatUint(n+1, 10)
+		return strings.Join(ids, ".")
+	}
+
+	return pre + ".1"
+}
+
+// mustRenderPrerelease renders a --pre-release-format template with the
+// counter n.
+func mustRenderPrerelease(format string, n interface{}) string {
+	t, err := template.New("pre-release-format").Parse(format)
+	if err != nil {
+		fatalf("Failed to parse template; %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, struct{ N interface{} }{n}); err != nil {
+		fatalf("Failed to render template; %v", err)
+	}
+
+	return buf.String()
+}
+
+// prereleaseCounter returns the counter of a prerelease rendered from a
+// --pre-release-format template, if it is one.
+func prereleaseCounter(format, pre string) (uint64, bool) {
+	// Render the template around a marker to learn what surrounds N.
+	marker := "\x00"
+	parts := strings.SplitN(mustRenderPrerelease(format, marker), marker, 2)
+	if len(parts) != 2 || len(pre) < len(parts[0])+len(parts[1]) || !strings.HasPrefix(pre, parts[0]) || !strings.HasSuffix(pre, parts[1]) {
+		return 0, false
+	}
+
+	n, err := strconv.ParseUint(pre[len(parts[0]):len(pre)-len(parts[1])], 10, 64)
+	return n, err == nil
+}
+
+// nextPrerelease bumps the prerelease counter of v. If v has no prerelease,
+// or one which does not start with id, a new series is started at 0; for a
+// version without prerelease that series belongs to the next patch.
+func nextPrerelease(v *semver.Version, id string) *semver.Version {
+	start := "0"
+	if id != "" {
+		start = id + ".0"
+	}
+
+	switch {
+	case v.Prerelease() == "":
+		return semver.New(v.Major(), v.Minor(), v.Patch()+1, start, "")
+	case id != "" && v.Prerelease() != id && !strings.HasPrefix(v.Prerelease(), id+"."):
+		return semver.New(v.Major(), v.Minor(), v.Patch(), start, "")
+	}
+
+	return semver.New(v.Major(), v.Minor(), v.Patch(), incPrerelease(v.Prerelease()), "")
 }
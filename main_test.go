@@ -0,0 +1,463 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// binary is the path of the semver binary built for the tests.
+var binary string
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "semver-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	binary = filepath.Join(dir, "semver")
+	build := exec.Command("go", "build", "-o", binary, ".")
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// run runs the binary with the arguments and empty stdin and returns its
+// stdout and exit code.
+func run(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+
+	return runWithInput(t, "", args...)
+}
+
+// runWithInput is run with input on stdin.
+func runWithInput(t *testing.T, input string, args ...string) (string, int) {
+	t.Helper()
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(binary, args...)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = &stdout
+
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return stdout.String(), exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("failed to run %v; %v", args, err)
+	}
+
+	return stdout.String(), 0
+}
+
+func TestGreatestAllFiltered(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"pre-release", []string{"greatest", "-p", "1.0.0-rc.1", "2.0.0-beta"}, 255},
+		{"build", []string{"greatest", "-b", "1.0.0+b.1", "2.0.0+b.2"}, 255},
+		{"error exit code", []string{"--error-exit-code", "3", "greatest", "-p", "1.0.0-rc.1"}, 3},
+		{"empty exit code", []string{"--empty-exit-code", "2", "greatest", "-p", "1.0.0-rc.1"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, code := run(t, tt.args...)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+			if out != "" {
+				t.Errorf("stdout = %q, want nothing", out)
+			}
+		})
+	}
+}
+
+func TestSetClearsComponent(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"prerelease", []string{"set", "prerelease", "1.2.3-rc.1", ""}},
+		{"metadata", []string{"set", "metadata", "1.2.3+b", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, code := run(t, tt.args...)
+			if code != 0 {
+				t.Errorf("exit code = %d, want 0", code)
+			}
+			if out != "1.2.3\n" {
+				t.Errorf("stdout = %q, want %q", out, "1.2.3\n")
+			}
+		})
+	}
+}
+
+func TestSatisfiesIncludePrereleaseWildcards(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		want       int
+	}{
+		{"1.3.1-rc.1", ">1.2.x", 0},
+		{"1.3.0-rc.1", ">1.2.x", 1},
+		{"1.2.9-rc.1", "<=1.2.x", 0},
+		{"1.3.0", "<=1.2.x", 1},
+		{"1.9.0-rc.1", "<2.x", 0},
+		{"2.0.1-rc.1", "<2.x", 1},
+		{"2.0.0-rc.1", ">=2.x", 1},
+		{"2.0.1-rc.1", ">=2.x", 0},
+		{"2.0.0-rc.1", "^1.0.0", 1},
+		{"2.0.0-rc.1", "1.x", 1},
+		{"2.0.0-rc.1", "~1.9", 1},
+		{"2.0.0-rc.1", "<=1.x", 1},
+		{"2.0.0-rc.1", "<2.x", 1},
+		{"1.5.0-rc.1", "^1.0.0", 0},
+		{"1.3.0-rc.1", "1.2 - 1.3", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint+" "+tt.version, func(t *testing.T) {
+			if _, code := run(t, "satisfies", "--include-prerelease", tt.version, tt.constraint); code != tt.want {
+				t.Errorf("exit code = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetStripV(t *testing.T) {
+	tests := []struct {
+		component string
+		version   string
+		want      string
+	}{
+		{"core", "v1.2.3", "1.2.3\n"},
+		{"prerelease", "1.0.0-vnext", "vnext\n"},
+		{"metadata", "1.0.0+v2", "v2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.component, func(t *testing.T) {
+			if out, _ := run(t, "get", "--strip-v", tt.component, tt.version); out != tt.want {
+				t.Errorf("stdout = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONOutput(t *testing.T) {
+	constraints := filepath.Join(filepath.Dir(binary), "constraints.txt")
+	if err := ioutil.WriteFile(constraints, []byte("^2\n>=1.2, <1.3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"constraint-expand", []string{"--json", "constraint-expand", "^1.2"}, `{"constraints":"^1.2","expanded":">=1.2.0, <2.0.0"}` + "\n"},
+		{"negate", []string{"--json", "negate", "^1.2.3"}, `{"constraints":"^1.2.3","negated":"<1.2.3 || >=2.0.0"}` + "\n"},
+		{"matches-any", []string{"--json", "-v", "matches-any", "--constraints-file", constraints, "1.2.5"}, `{"matches":true,"constraint":">=1.2, <1.3"}` + "\n"},
+		{"matches-any none", []string{"--json", "matches-any", "--constraints-file", constraints, "1.5.0"}, `{"matches":false}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if out, _ := run(t, tt.args...); out != tt.want {
+				t.Errorf("stdout = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestBumpAutoHeaderOnly(t *testing.T) {
+	tests := []struct {
+		name    string
+		commits string
+		want    string
+	}{
+		{"body line", "docs: update readme\n\nfix: typo mentioned in body\n\x00chore: tidy\n", "1.0.0\n"},
+		{"headers", "docs: update readme\nfix: typo\n", "1.0.1\n"},
+		{"footer", "docs: update readme\n\nBREAKING CHANGE: moved\n\x00", "2.0.0\n"},
+		{"git log", "commit 0123456789abcdef\nAuthor: A <a@b>\n\n    docs: readme\n\n    feat: mentioned in body\n", "1.0.0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(filepath.Dir(binary), "commits.txt")
+			if err := ioutil.WriteFile(file, []byte(tt.commits), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if out, _ := run(t, "bump", "--level", "auto", "--commits-file", file, "1.0.0"); out != tt.want {
+				t.Errorf("stdout = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountConstraintArgument(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+		code int
+	}{
+		{"satisfies", []string{"count", "-s", ">=1", "1.0.0", "0.5.0"}, "1\n", 0},
+		{"versions only", []string{"count", "1.0.0", "0.5.0"}, "2\n", 0},
+		{"constraint argument", []string{"count", ">=1", "1.0.0", "0.5.0"}, "", 255},
+		{"constraint argument ignored invalid", []string{"count", "--ignore-invalid", ">=1", "1.0.0"}, "", 255},
+		// A version is no constraint, even if it reads as one.
+		{"ambiguous", []string{"count", "1.0.0", "1.0.0", "2.0.0"}, "3\n", 0},
+		{"ambiguous satisfies", []string{"count", "-s", "1.0.0", "1.0.0", "2.0.0"}, "1\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, code := run(t, tt.args...)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+			if out != tt.want {
+				t.Errorf("stdout = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestNullDelimitedInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"nul", "2.0.0\x001.0.0\x00"},
+		{"newline", "v2.0.0\nv1.0.0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if out, _ := runWithInput(t, tt.input, "-0", "sort"); out != "1.0.0\x002.0.0\x00" {
+				t.Errorf("stdout = %q, want %q", out, "1.0.0\x002.0.0\x00")
+			}
+		})
+	}
+}
+
+func TestWriteKeepsLeadingV(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"inc", []string{"inc", "--write", "patch"}, "v1.2.4\n"},
+		{"inc prefix", []string{"inc", "--write", "--prefix", "release-", "patch"}, "release-1.2.4\n"},
+		{"set", []string{"set", "--write", "prerelease", "rc.1"}, "v1.2.3-rc.1\n"},
+		{"update", []string{"update", "minor"}, "v1.3.0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(filepath.Dir(binary), "VERSION")
+			if err := ioutil.WriteFile(file, []byte("v1.2.3\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			out, _ := run(t, append(tt.args, "--file", file)...)
+			if out != tt.want {
+				t.Errorf("stdout = %q, want %q", out, tt.want)
+			}
+
+			written, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(written) != tt.want {
+				t.Errorf("file = %q, want %q", written, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatJSONParsesTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+		code int
+	}{
+		{"valid", []string{"--json", "format", "{{.Major}}", "1.2.3"}, `{"major":1,"minor":2,"patch":3,"prerelease":"","metadata":"","original":"1.2.3"}` + "\n", 0},
+		{"invalid", []string{"--json", "format", "{{", "1.2.3"}, "", 255},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, code := run(t, tt.args...)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+			if out != tt.want {
+				t.Errorf("stdout = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatTemplateFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+		code int
+	}{
+		{"flag", []string{"format", "--template", "{{.Major}}", "1.2.3"}, "1\n", 0},
+		{"argument", []string{"format", "{{.Major}}", "1.2.3"}, "1\n", 0},
+		{"flag without version", []string{"format", "--template", "{{.Major}}"}, "", 255},
+		{"argument without version", []string{"format", "{{.Major}}"}, "", 255},
+		{"no template", []string{"format"}, "", 255},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, code := run(t, tt.args...)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+			if out != tt.want {
+				t.Errorf("stdout = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"major", []string{"--yaml", "get", "major", "1.2.3"}, "1\n"},
+		{"patch", []string{"--yaml", "get", "patch", "1.2.3"}, "3\n"},
+		{"numeric prerelease", []string{"--yaml", "get", "prerelease", "1.2.3-1"}, "\"1\"\n"},
+		{"prerelease", []string{"--yaml", "get", "prerelease", "1.2.3-rc"}, "rc\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if out, _ := run(t, tt.args...); out != tt.want {
+				t.Errorf("stdout = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestConstraintExpand(t *testing.T) {
+	tests := []struct {
+		constraint string
+		want       string
+	}{
+		{"^1.2.3", ">=1.2.3, <2.0.0\n"},
+		{"^0.2.3", ">=0.2.3, <0.3.0\n"},
+		{"~1.2.3", ">=1.2.3, <1.3.0\n"},
+		{"1.2.x", ">=1.2.0, <1.3.0\n"},
+		{"1.2 - 1.4", ">=1.2.0, <1.5.0\n"},
+		{">1.2", ">=1.3.0\n"},
+		{"<=1.x", "<2.0.0\n"},
+		{"^1 || ~2.1", ">=1.0.0, <2.0.0 || >=2.1.0, <2.2.0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			if out, _ := run(t, "constraint-expand", tt.constraint); out != tt.want {
+				t.Errorf("stdout = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegate(t *testing.T) {
+	tests := []struct {
+		constraint string
+		want       string
+		code       int
+	}{
+		{"^1.2.3", "<1.2.3 || >=2.0.0\n", 0},
+		{">=1.0.0", "<1.0.0\n", 0},
+		{"<1.0.0 || >=2.0.0", ">=1.0.0, <2.0.0\n", 0},
+		{"1.2.3", "!=1.2.3\n", 0},
+		{"*", "", 255},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			out, code := run(t, "negate", tt.constraint)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+			if out != tt.want {
+				t.Errorf("stdout = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetOperationSeparator(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"difference", []string{"difference", "1.0.0", "2.0.0", "--", "2.0.0"}, "1.0.0\n"},
+		{"intersection", []string{"intersection", "1.0.0", "2.0.0", "--", "2.0.0", "3.0.0"}, "2.0.0\n"},
+		{"union", []string{"union", "2.0.0", "--", "1.0.0", "2.0.0"}, "2.0.0\n1.0.0\n"},
+		{"global flag", []string{"-0", "difference", "1.0.0", "2.0.0", "--", "2.0.0"}, "1.0.0\x00"},
+		{"empty A", []string{"union", "--", "1.0.0"}, "1.0.0\n"},
+		{"empty B", []string{"difference", "1.0.0", "--"}, "1.0.0\n"},
+		{"custom separator", []string{"difference", "--separator", ",", "1.0.0", "2.0.0", ",", "2.0.0"}, "1.0.0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if out, _ := run(t, tt.args...); out != tt.want {
+				t.Errorf("stdout = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextPre(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"release", []string{"next", "pre", "1.2.3"}, "1.2.4-0\n"},
+		{"release pre-id", []string{"next", "--pre-id", "rc", "pre", "1.2.3"}, "1.2.4-rc.0\n"},
+		{"prerelease", []string{"next", "--pre-id", "rc", "pre", "1.2.4-rc.0"}, "1.2.4-rc.1\n"},
+		{"other pre-id", []string{"next", "--pre-id", "rc", "pre", "1.2.4-beta.3"}, "1.2.4-rc.0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if out, _ := run(t, tt.args...); out != tt.want {
+				t.Errorf("stdout = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
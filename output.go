@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	semver "github.com/Masterminds/semver/v3"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// versionJSON is the JSON representation of a version.
+type versionJSON struct {
+	Version    string `json:"version"`
+	Major      uint64 `json:"major"`
+	Minor      uint64 `json:"minor"`
+	Patch      uint64 `json:"patch"`
+	Prerelease string `json:"prerelease"`
+	Metadata   string `json:"metadata"`
+}
+
+func newVersionJSON(v *semver.Version) versionJSON {
+	return versionJSON{
+		Version:    v.String(),
+		Major:      v.Major(),
+		Minor:      v.Minor(),
+		Patch:      v.Patch(),
+		Prerelease: v.Prerelease(),
+		Metadata:   v.Metadata(),
+	}
+}
+
+// linesPrinted tells printLine whether the terminator of a previous line is
+// still owed with --no-newline.
+var linesPrinted bool
+
+// printLine prints its operands followed by the line terminator, a NUL
+// byte with --null. With --no-newline the terminator of a line is only
+// printed once another line follows, so the output does not end with one.
+func printLine(a ...interface{}) {
+	end := "\n"
+	if *nullDelim {
+		end = "\x00"
+	}
+
+	if *noNewline {
+		if linesPrinted {
+			fmt.Print(end)
+		}
+		fmt.Print(fmt.Sprint(a...))
+		linesPrinted = true
+		return
+	}
+
+	fmt.Print(fmt.Sprint(a...) + end)
+}
+
+// printLinef is printLine with a format string.
+func printLinef(format string, a ...interface{}) {
+	printLine(fmt.Sprintf(format, a...))
+}
+
+// printJSON prints v as JSON, or as YAML with --yaml.
+func printJSON(v interface{}) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(v); err != nil {
+		fatalf("Failed to encode JSON; %v", err)
+	}
+
+	if *yamlOutput {
+		printLine(strings.TrimSuffix(jsonToYAML(buf.Bytes()), "\n"))
+		return
+	}
+
+	printLine(strings.TrimSuffix(buf.String(), "\n"))
+}
+
+// jsonToYAML converts JSON to block style YAML. JSON is valid YAML, so it is
+// decoded into a node tree, which keeps the order of the keys.
+func jsonToYAML(data []byte) string {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		fatalf("Failed to encode YAML; %v", err)
+	}
+	blockStyle(&node)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		fatalf("Failed to encode YAML; %v", err)
+	}
+
+	return buf.String()
+}
+
+// blockStyle drops the flow style and quoting the JSON syntax left on the
+// nodes. The encoder still quotes strings which would read as another type.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+// printVersion prints the version with --output-prefix and the given prefix,
+// or all of its components with --json.
+func printVersion(v *semver.Version, prefix string) {
+	if *jsonOutput {
+		printJSON(newVersionJSON(v))
+		return
+	}
+
+	printLine(*outputPrefix + prefix + v.String())
+}
+
+// printSelectedVersion prints the version which was picked from a list of
+// count versions, e.g. the greatest one.
+func printSelectedVersion(v *semver.Version, count int) {
+	if *jsonOutput {
+		printJSON(struct {
+			Version string `json:"version"`
+			Count   int    `json:"count"`
+		}{v.String(), count})
+		return
+	}
+
+	printLine(*outputPrefix + v.String())
+}
+
+// printVersions prints the versions one per line, joined with the delimiter
+// if one is given, or as a JSON list.
+func printVersions(vs []semver.Version, delimiter string) {
+	list := []string{}
+	for _, v := range vs {
+		list = append(list, v.String())
+	}
+
+	printVersionStrings(list, delimiter)
+}
+
+// printVersionStrings is printVersions for versions which are already
+// formatted.
+func printVersionStrings(list []string, delimiter string) {
+	if *jsonOutput {
+		printJSON(struct {
+			Versions []string `json:"versions"`
+		}{list})
+		return
+	}
+
+	if *outputPrefix != "" {
+		prefixed := []string{}
+		for _, v := range list {
+			prefixed = append(prefixed, *outputPrefix+v)
+		}
+		list = prefixed
+	}
+
+	if delimiter != "" {
+		if len(list) > 0 {
+			printLine(strings.Join(list, delimiter))
+		}
+		return
+	}
+
+	for _, v := range list {
+		printLine(v)
+	}
+}
+
+// printPrerelease prints the prerelease of a tested version if verbose, or
+// whether it is stable with --json.
+func printPrerelease(pre string) {
+	if *jsonOutput {
+		printJSON(struct {
+			Stable     bool   `json:"stable"`
+			Prerelease string `json:"prerelease"`
+		}{pre == "", pre})
+	} else if *verbose && pre != "" {
+		printLine(pre)
+	}
+}
+
+// printEnv prints the components of the version as shell variable
+// assignments like SEMVER_MAJOR=1, with the given name prefix.
+func printEnv(v *semver.Version, prefix string) {
+	for _, name := range componentNames {
+		value, _ := componentValue(v, name)
+		printLinef("%s%s=%s", prefix, strings.ToUpper(name), shellQuote(value))
+	}
+}
+
+// shellWord matches a value which needs no quoting in a shell, including
+// the empty one.
+var shellWord = regexp.MustCompile(`^[A-Za-z0-9._+-]*$`)
+
+// shellQuote quotes s for a POSIX shell unless it is a plain word.
+func shellQuote(s string) string {
+	if shellWord.MatchString(s) {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// printCount prints the number of versions.
+func printCount(n int) {
+	if *jsonOutput {
+		printJSON(struct {
+			Count int `json:"count"`
+		}{n})
+		return
+	}
+
+	printLine(n)
+}
+
+// printClauses prints the clauses of a constraint as aligned columns.
+func printClauses(clauses []clauseResult) {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	for _, c := range clauses {
+		status := "ok"
+		if !c.Satisfied {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.Clause, status, c.Reason)
+	}
+	w.Flush()
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if line != "" {
+			printLine(strings.TrimRight(line, " "))
+		}
+	}
+}